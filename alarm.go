@@ -0,0 +1,79 @@
+package main
+
+import "time"
+
+const (
+	// alarmStartVolume is the volume of the first beep of an escalating
+	// alarm
+	alarmStartVolume = 0.25
+	// alarmEscalateSteps is the number of repeats until an escalating alarm
+	// reaches full volume
+	alarmEscalateSteps = 4
+)
+
+// Alarm repeats the beep until it is acknowledged, optionally getting
+// louder with every repeat
+type Alarm struct {
+	Every    time.Duration // time between repeats
+	Escalate bool          // start quiet and get louder
+	Hold     bool          // hold the next interval until acknowledged
+	Active   bool          // sounding, not yet acknowledged
+	Since    time.Time     // when the alarm started sounding
+	Beeps    int           // beeps played since then
+	next     time.Time
+}
+
+// NewAlarm creates an alarm repeating every interval
+func NewAlarm(every time.Duration, escalate, hold bool) *Alarm {
+	return &Alarm{Every: every, Escalate: escalate, Hold: hold}
+}
+
+// Start sounds the alarm and returns the volume of its beep. An alarm that
+// is still sounding carries on escalating.
+func (a *Alarm) Start(now time.Time) float64 {
+	if !a.Active {
+		a.Active = true
+		a.Since = now
+		a.Beeps = 0
+	}
+	return a.ring(now)
+}
+
+// ring counts a beep and returns its volume
+func (a *Alarm) ring(now time.Time) float64 {
+	volume := a.Volume()
+	a.Beeps++
+	a.next = now.Add(a.Every)
+	return volume
+}
+
+// Volume returns the volume of the next beep
+func (a *Alarm) Volume() float64 {
+	if !a.Escalate {
+		return 1
+	}
+	return min(1, alarmStartVolume+(1-alarmStartVolume)*float64(a.Beeps)/alarmEscalateSteps)
+}
+
+// Next returns the time until the next repeat, false if the alarm isn't
+// sounding
+func (a *Alarm) Next(now time.Time) (time.Duration, bool) {
+	if !a.Active {
+		return 0, false
+	}
+	return max(a.next.Sub(now), 0), true
+}
+
+// Repeat returns the volume of the next beep once it is due
+func (a *Alarm) Repeat(now time.Time) (float64, bool) {
+	if !a.Active || now.Before(a.next) {
+		return 0, false
+	}
+	return a.ring(now), true
+}
+
+// Acknowledge silences the alarm and returns how long it was sounding
+func (a *Alarm) Acknowledge(now time.Time) time.Duration {
+	a.Active = false
+	return now.Sub(a.Since)
+}
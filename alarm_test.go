@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAlarm tests repeating and escalating the alarm until acknowledged
+func TestAlarm(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("repeats at full volume", func(t *testing.T) {
+		a := NewAlarm(5*time.Second, false, false)
+		if _, ok := a.Next(start); ok {
+			t.Fatal("Next() ok before the alarm started")
+		}
+		if volume := a.Start(start); volume != 1 {
+			t.Errorf("Start() = %v, want 1", volume)
+		}
+		if next, ok := a.Next(start.Add(2 * time.Second)); !ok || next != 3*time.Second {
+			t.Errorf("Next() = %v, %v, want 3s, true", next, ok)
+		}
+		if _, ok := a.Repeat(start.Add(4 * time.Second)); ok {
+			t.Error("Repeat() ok before the repeat is due")
+		}
+		if volume, ok := a.Repeat(start.Add(5 * time.Second)); !ok || volume != 1 {
+			t.Errorf("Repeat() = %v, %v, want 1, true", volume, ok)
+		}
+		if a.Beeps != 2 {
+			t.Errorf("Beeps = %d, want 2", a.Beeps)
+		}
+		if sounded := a.Acknowledge(start.Add(7 * time.Second)); sounded != 7*time.Second {
+			t.Errorf("Acknowledge() = %v, want 7s", sounded)
+		}
+		if _, ok := a.Repeat(start.Add(10 * time.Second)); ok {
+			t.Error("Repeat() ok after acknowledging")
+		}
+	})
+
+	t.Run("escalates", func(t *testing.T) {
+		a := NewAlarm(time.Second, true, false)
+		volumes := []float64{a.Start(start)}
+		for i := 1; i <= 5; i++ {
+			volume, _ := a.Repeat(start.Add(time.Duration(i) * time.Second))
+			volumes = append(volumes, volume)
+		}
+		expected := []float64{0.25, 0.4375, 0.625, 0.8125, 1, 1}
+		for i := range expected {
+			if volumes[i] != expected[i] {
+				t.Errorf("beep %d volume = %v, want %v", i+1, volumes[i], expected[i])
+			}
+		}
+
+		// A beep while the alarm sounds carries on escalating
+		if volume := a.Start(start.Add(6 * time.Second)); volume != 1 || a.Since != start {
+			t.Errorf("Start() = %v since %v, want 1 since %v", volume, a.Since, start)
+		}
+		a.Acknowledge(start.Add(7 * time.Second))
+		if volume := a.Start(start.Add(8 * time.Second)); volume != 0.25 {
+			t.Errorf("Start() after acknowledging = %v, want 0.25", volume)
+		}
+	})
+}
+
+// TestFormatAlarmOutput tests alarm repeats and ticks while an alarm sounds
+func TestFormatAlarmOutput(t *testing.T) {
+	timestamp := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	data := TemplateData{Remaining: 25 * time.Minute, Index: 1, Count: 1, State: "alarm", Alarm: true, AlarmBeeps: 3}
+	ticking := data
+	ticking.State = "counting"
+	held := data
+	held.State = "paused"
+
+	tests := []struct {
+		name     string
+		mode     OutputMode
+		data     TemplateData
+		expected string
+	}{
+		{"json", ModeJSON, data, `{"text":"ALARM","tooltip":"Alarm beep #3, click to acknowledge","class":"alarm","remaining":0}`},
+		{"json tick", ModeJSON, ticking, `{"text":"25m 0s","tooltip":"25m 0s","class":"alarm","remaining":1500}`},
+		{"json held", ModeJSON, held, `{"text":"25m 0s","tooltip":"25m 0s","class":"alarm","remaining":1500}`},
+		{"watch", ModeWatch, data, "ALARM"},
+		{"tmux", ModeTmux, ticking, "#[fg=#eba0ac]25m 0s#[default]"},
+		{"verbose", ModeVerbose, data, "\r[10:30:00] Alarm beep #3, waiting to be acknowledged              \n"},
+		{"default", ModeDefault, data, "ALARM 2025-01-01T10:30:00Z\n"},
+		{"events", ModeEvents, data, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := OutputConfig{Mode: tt.mode, MinutesList: []int{25}, SecondsList: []int{0}, IntervalCount: 1}
+			if result := FormatOutput(config, tt.data, timestamp); result != tt.expected {
+				t.Errorf("FormatOutput() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestStatusFromEventAlarm tests the API state while an alarm sounds
+func TestStatusFromEventAlarm(t *testing.T) {
+	if state := statusFromEvent(Event{Paused: true, Alarm: true}).State; state != "alarm" {
+		t.Errorf("State = %q, want alarm", state)
+	}
+	if state := statusFromEvent(Event{Paused: true}).State; state != "paused" {
+		t.Errorf("State = %q, want paused", state)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// SuspendPolicy decides what happens to a beep that fell into a suspend
+type SuspendPolicy string
+
+const (
+	// SuspendFire counts the suspended time and plays a missed beep late
+	SuspendFire SuspendPolicy = "fire"
+	// SuspendSkip counts the suspended time and moves past a missed beep
+	// without playing it
+	SuspendSkip SuspendPolicy = "skip"
+	// SuspendPause doesn't count the suspended time, as if the timer had
+	// been paused
+	SuspendPause SuspendPolicy = "pause"
+)
+
+const (
+	// clockJumpThreshold is how far the wall clock has to move beyond the
+	// time bleep was running before it counts as a suspend or clock change
+	clockJumpThreshold = 5 * time.Second
+	// beepLateTolerance is how late a beep may be handled and still start
+	// the next interval at the end of the previous one
+	beepLateTolerance = 2 * time.Second
+)
+
+// parseSuspendPolicy parses an -on-suspend value
+func parseSuspendPolicy(s string) (SuspendPolicy, error) {
+	switch policy := SuspendPolicy(s); policy {
+	case SuspendFire, SuspendSkip, SuspendPause:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid -on-suspend value %q (want fire, skip or pause)", s)
+}
+
+// ClockJump is a suspend or clock change noticed between two checks
+type ClockJump struct {
+	Wall time.Duration // how far the wall clock moved
+	Mono time.Duration // how far the monotonic clock moved
+	Ran  time.Duration // how long bleep was expected to run between checks
+}
+
+// Jump returns how far the wall clock moved beyond the time bleep was
+// running; negative when the clock was set back
+func (j ClockJump) Jump() time.Duration {
+	return j.Wall - j.Ran
+}
+
+// Elapsed returns the time the timer counts for the jump under the policy
+func (j ClockJump) Elapsed(policy SuspendPolicy) time.Duration {
+	if policy == SuspendPause {
+		return j.Ran
+	}
+	// A suspend advances the wall clock, and on some systems the monotonic
+	// clock as well. A clock set back moves neither.
+	return max(j.Wall, j.Mono)
+}
+
+// ClockWatch detects suspends and clock changes by comparing how far the
+// wall clock and the monotonic clock moved between checks. On Linux the
+// monotonic clock stops during suspend, elsewhere both clocks move on while
+// bleep wasn't running; either way the wall clock is ahead of the time bleep
+// was expected to run.
+type ClockWatch struct {
+	Interval time.Duration // expected time between checks
+	last     time.Time
+}
+
+// NewClockWatch creates a clock watch for checks every interval
+func NewClockWatch(interval time.Duration) *ClockWatch {
+	return &ClockWatch{Interval: interval, last: time.Now()}
+}
+
+// Check compares the clocks since the last check and reports a jump if
+// they disagree by more than clockJumpThreshold
+func (c *ClockWatch) Check(now time.Time) (ClockJump, bool) {
+	j := ClockJump{
+		Wall: now.Round(0).Sub(c.last.Round(0)),
+		Mono: now.Sub(c.last),
+	}
+	j.Ran = min(j.Mono, c.Interval)
+	c.last = now
+	jump := j.Jump()
+	return j, jump > clockJumpThreshold || jump < -clockJumpThreshold
+}
+
+// ClockJumped adjusts the timer for a suspend or clock change and reports
+// whether a beep was missed. Timers that follow the wall clock (-until,
+// -align) keep their beep times; interval timers count the suspended time
+// unless the policy is SuspendPause.
+func (ts *TimerState) ClockJumped(j ClockJump, policy SuspendPolicy) bool {
+	if ts.Paused {
+		return false
+	}
+	now := time.Now()
+	if ts.Schedule != nil || ts.Aligned {
+		missed := !ts.NextBeep.Round(0).After(now.Round(0))
+		if !missed && ts.Aligned {
+			// Move the monotonic deadline back onto the boundary
+			ts.alignToClock()
+		}
+		return missed
+	}
+	// Remaining() only moved on by the monotonic time since the last check
+	remaining := ts.Remaining() + j.Mono - j.Elapsed(policy)
+	ts.SetRemaining(remaining)
+	return remaining <= 0
+}
+
+// SkipMissed moves on past a beep missed during a suspend without beeping
+func (ts *TimerState) SkipMissed() {
+	if ts.Aligned {
+		ts.alignToClock()
+		return
+	}
+	ts.Skip()
+}
+
+// ClockJumped adjusts the stopwatch for a suspend or clock change and
+// reports whether a beep was missed. The suspended time is counted unless
+// the policy is SuspendPause.
+func (sw *Stopwatch) ClockJumped(j ClockJump, policy SuspendPolicy) bool {
+	if sw.Paused {
+		return false
+	}
+	sw.Offset = sw.Elapsed() - j.Mono + j.Elapsed(policy)
+	sw.Resumed = time.Now()
+	return sw.BeepDue()
+}
+
+// SkipMissed moves the next beep past the elapsed time without beeping
+func (sw *Stopwatch) SkipMissed() {
+	for sw.BeepDue() {
+		sw.NextBeep += sw.Every
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseSuspendPolicy tests the parseSuspendPolicy function
+func TestParseSuspendPolicy(t *testing.T) {
+	for _, s := range []string{"fire", "skip", "pause"} {
+		if policy, err := parseSuspendPolicy(s); err != nil || string(policy) != s {
+			t.Errorf("parseSuspendPolicy(%q) = %q, %v", s, policy, err)
+		}
+	}
+	if _, err := parseSuspendPolicy("ignore"); err == nil {
+		t.Error("parseSuspendPolicy(\"ignore\") expected error, got nil")
+	}
+}
+
+// TestClockWatch tests detecting a jump between checks
+func TestClockWatch(t *testing.T) {
+	c := NewClockWatch(time.Second)
+	c.last = time.Now().Add(-time.Second)
+	if j, ok := c.Check(time.Now()); ok {
+		t.Errorf("Check() after a regular tick reported %+v", j)
+	}
+
+	// Without monotonic readings both clocks moved by an hour, as after a
+	// suspend on systems whose monotonic clock keeps running
+	c.last = time.Now().Add(-time.Hour).Round(0)
+	j, ok := c.Check(time.Now())
+	if !ok {
+		t.Fatal("Check() after an hour reported no jump")
+	}
+	if jump := j.Jump().Round(time.Minute); jump != time.Hour {
+		t.Errorf("Jump() = %v, want about 1h", jump)
+	}
+}
+
+// TestClockJumpElapsed tests the time counted for a jump under each policy
+func TestClockJumpElapsed(t *testing.T) {
+	tests := []struct {
+		name     string
+		jump     ClockJump
+		policy   SuspendPolicy
+		expected time.Duration
+	}{
+		{"suspend, monotonic clock stopped", ClockJump{Wall: time.Hour, Mono: time.Second, Ran: time.Second}, SuspendFire, time.Hour},
+		{"suspend, monotonic clock running", ClockJump{Wall: time.Hour, Mono: time.Hour, Ran: time.Second}, SuspendSkip, time.Hour},
+		{"clock set back", ClockJump{Wall: -time.Hour, Mono: time.Second, Ran: time.Second}, SuspendFire, time.Second},
+		{"pause", ClockJump{Wall: time.Hour, Mono: time.Hour, Ran: time.Second}, SuspendPause, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.jump.Elapsed(tt.policy); got != tt.expected {
+				t.Errorf("Elapsed(%s) = %v, want %v", tt.policy, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestTimerStateClockJumped tests how a countdown handles a suspend
+func TestTimerStateClockJumped(t *testing.T) {
+	// Suspended with the monotonic clock stopped, as on Linux
+	suspend := func(d time.Duration) ClockJump {
+		return ClockJump{Wall: d, Mono: time.Second, Ran: time.Second}
+	}
+	newState := func(paused bool) *TimerState {
+		ts := NewTimerState([]time.Duration{25 * time.Minute}, []int{25}, []int{0}, paused)
+		ts.SetRemaining(10 * time.Minute)
+		return ts
+	}
+
+	tests := []struct {
+		name      string
+		paused    bool
+		jump      ClockJump
+		policy    SuspendPolicy
+		missed    bool
+		remaining time.Duration
+	}{
+		{"short suspend", false, suspend(5 * time.Minute), SuspendFire, false, 5*time.Minute + time.Second},
+		{"beep missed", false, suspend(time.Hour), SuspendFire, true, 0},
+		{"beep missed, skip", false, suspend(time.Hour), SuspendSkip, true, 0},
+		{"pause", false, suspend(time.Hour), SuspendPause, false, 10 * time.Minute},
+		{"clock set back", false, suspend(-time.Hour), SuspendFire, false, 10 * time.Minute},
+		{"paused timer", true, suspend(time.Hour), SuspendFire, false, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newState(tt.paused)
+			if missed := ts.ClockJumped(tt.jump, tt.policy); missed != tt.missed {
+				t.Errorf("ClockJumped() = %v, want %v", missed, tt.missed)
+			}
+			if tt.missed {
+				return
+			}
+			if remaining := ts.Remaining(); remaining < tt.remaining-time.Second || remaining > tt.remaining {
+				t.Errorf("Remaining() = %v, expected about %v", remaining, tt.remaining)
+			}
+		})
+	}
+
+	// Wall-clock timers keep their beep time
+	u := &UntilSchedule{Target: time.Now().Add(-time.Minute), Location: time.Local}
+	ts := NewScheduledTimerState(u, false)
+	ts.NextBeep = u.Target
+	if !ts.ClockJumped(suspend(time.Hour), SuspendPause) {
+		t.Error("ClockJumped() should report the missed -until beep")
+	}
+	ts.SkipMissed()
+	if !ts.Done {
+		t.Error("SkipMissed() should end a one-off -until timer")
+	}
+}
+
+// TestStopwatchClockJumped tests how the stopwatch handles a suspend
+func TestStopwatchClockJumped(t *testing.T) {
+	// The stopwatch ran for 2m, the last second of it since the last check
+	jump := ClockJump{Wall: 10 * time.Minute, Mono: time.Second, Ran: time.Second}
+
+	sw := newTestStopwatch(5*time.Minute, 2*time.Minute)
+	if !sw.ClockJumped(jump, SuspendFire) {
+		t.Fatal("ClockJumped() should report the missed beeps")
+	}
+	if elapsed := sw.Elapsed().Round(time.Second); elapsed != 11*time.Minute+59*time.Second {
+		t.Errorf("Elapsed() = %v, want 11m59s", elapsed)
+	}
+	// Only one of the missed beeps is played
+	sw.TriggerBeep()
+	if sw.BeepCount != 1 || sw.NextBeep != 15*time.Minute {
+		t.Errorf("after beep: count %d, next %v", sw.BeepCount, sw.NextBeep)
+	}
+
+	sw = newTestStopwatch(5*time.Minute, 2*time.Minute)
+	sw.ClockJumped(jump, SuspendSkip)
+	sw.SkipMissed()
+	if sw.BeepCount != 0 || sw.NextBeep != 15*time.Minute {
+		t.Errorf("after skip: count %d, next %v", sw.BeepCount, sw.NextBeep)
+	}
+
+	sw = newTestStopwatch(5*time.Minute, 2*time.Minute)
+	if sw.ClockJumped(jump, SuspendPause) {
+		t.Error("ClockJumped() with pause policy reported a missed beep")
+	}
+	if elapsed := sw.Elapsed().Round(time.Second); elapsed != 2*time.Minute {
+		t.Errorf("Elapsed() = %v, want 2m", elapsed)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"sync"
+)
+
+// Speaker is the position a channel is meant to be played from
+type Speaker int
+
+// Speaker positions, in the order of WAV channel masks
+const (
+	FrontLeft Speaker = iota
+	FrontRight
+	FrontCenter
+	LowFrequency
+	BackLeft
+	BackRight
+	FrontLeftCenter
+	FrontRightCenter
+	BackCenter
+	SideLeft
+	SideRight
+)
+
+// defaultLayouts are the channel orders of WAV and FLAC files by channel
+// count
+var defaultLayouts = map[int][]Speaker{
+	1: {FrontCenter},
+	2: {FrontLeft, FrontRight},
+	3: {FrontLeft, FrontRight, FrontCenter},
+	4: {FrontLeft, FrontRight, BackLeft, BackRight},
+	5: {FrontLeft, FrontRight, FrontCenter, BackLeft, BackRight},
+	6: {FrontLeft, FrontRight, FrontCenter, LowFrequency, BackLeft, BackRight},
+	7: {FrontLeft, FrontRight, FrontCenter, LowFrequency, BackCenter, SideLeft, SideRight},
+	8: {FrontLeft, FrontRight, FrontCenter, LowFrequency, BackLeft, BackRight, SideLeft, SideRight},
+}
+
+// vorbisLayouts are the channel orders of Vorbis streams, which put the
+// center between left and right and the LFE channel last
+var vorbisLayouts = map[int][]Speaker{
+	1: {FrontCenter},
+	2: {FrontLeft, FrontRight},
+	3: {FrontLeft, FrontCenter, FrontRight},
+	4: {FrontLeft, FrontRight, BackLeft, BackRight},
+	5: {FrontLeft, FrontCenter, FrontRight, BackLeft, BackRight},
+	6: {FrontLeft, FrontCenter, FrontRight, BackLeft, BackRight, LowFrequency},
+	7: {FrontLeft, FrontCenter, FrontRight, SideLeft, SideRight, BackCenter, LowFrequency},
+	8: {FrontLeft, FrontCenter, FrontRight, SideLeft, SideRight, BackLeft, BackRight, LowFrequency},
+}
+
+// maskLayout returns the speakers of a WAV channel mask, or nil if it
+// doesn't describe channels speakers bleep knows
+func maskLayout(mask uint32, channels int) []Speaker {
+	if mask >= 1<<(SideRight+1) || bits.OnesCount32(mask) != channels {
+		return nil
+	}
+	var layout []Speaker
+	for speaker := FrontLeft; speaker <= SideRight; speaker++ {
+		if mask&(1<<speaker) != 0 {
+			layout = append(layout, speaker)
+		}
+	}
+	return layout
+}
+
+// centerGain is the gain of a center or surround channel in each side of a
+// stereo downmix (-3 dB)
+const centerGain = math.Sqrt2 / 2
+
+// stereoGains are the gains of each speaker in the left and right output;
+// the LFE channel is left out
+var stereoGains = map[Speaker][2]float64{
+	FrontLeft:        {1, 0},
+	FrontRight:       {0, 1},
+	FrontCenter:      {centerGain, centerGain},
+	FrontLeftCenter:  {1, 0},
+	FrontRightCenter: {0, 1},
+	BackLeft:         {centerGain, 0},
+	BackRight:        {0, centerGain},
+	SideLeft:         {centerGain, 0},
+	SideRight:        {0, centerGain},
+	BackCenter:       {0.5, 0.5},
+}
+
+// layout returns the speakers of the channels: the decoder's, or the
+// default order for the channel count. Unknown layouts alternate left and
+// right.
+func (s *Sound) layout() []Speaker {
+	if len(s.Layout) == s.Channels {
+		return s.Layout
+	}
+	if layout, ok := defaultLayouts[s.Channels]; ok {
+		return layout
+	}
+	layout := make([]Speaker, s.Channels)
+	for c := range layout {
+		layout[c] = FrontLeft + Speaker(c%2)
+	}
+	return layout
+}
+
+// Stereo mixes the channels down (or mono up) to interleaved stereo. Mono
+// plays at full volume on both sides. More channels are mixed by speaker
+// position and scaled so the mix can't clip.
+func (s *Sound) Stereo() []float32 {
+	frames := s.Frames()
+	out := make([]float32, frames*2)
+	if s.Channels == 1 {
+		for i, v := range s.Samples {
+			out[i*2], out[i*2+1] = v, v
+		}
+		return out
+	}
+
+	// The gain of each channel in each side, normalized so that each side's
+	// gains add up to at most 1
+	layout := s.layout()
+	gains := make([][2]float32, s.Channels)
+	var sums [2]float64
+	for _, speaker := range layout {
+		g := stereoGains[speaker]
+		sums[0] += g[0]
+		sums[1] += g[1]
+	}
+	for c, speaker := range layout {
+		for side := range 2 {
+			gains[c][side] = float32(stereoGains[speaker][side] / max(1, sums[side]))
+		}
+	}
+
+	for i := 0; i < frames; i++ {
+		frame := s.Samples[i*s.Channels : (i+1)*s.Channels]
+		var left, right float32
+		for c, v := range frame {
+			left += v * gains[c][0]
+			right += v * gains[c][1]
+		}
+		out[i*2], out[i*2+1] = left, right
+	}
+	return out
+}
+
+const (
+	// resampleZeroCrossings is the half width of the resampling filter, in
+	// zero crossings of its sinc
+	resampleZeroCrossings = 16
+	// resampleTableSteps is the number of filter values kept per zero
+	// crossing; values in between are interpolated
+	resampleTableSteps = 512
+)
+
+// resampleTable holds one side of the resampling filter: a sinc under a
+// Blackman window
+var resampleTable = sync.OnceValue(func() []float64 {
+	n := resampleZeroCrossings * resampleTableSteps
+	table := make([]float64, n+2) // ends in zeros for the interpolation
+	for i := 0; i <= n; i++ {
+		x := float64(i) / resampleTableSteps
+		sinc := 1.0
+		if i > 0 {
+			sinc = math.Sin(math.Pi*x) / (math.Pi * x)
+		}
+		w := math.Pi * x / resampleZeroCrossings
+		table[i] = sinc * (0.42 + 0.5*math.Cos(w) + 0.08*math.Cos(2*w))
+	}
+	return table
+})
+
+// resampleFilter returns the filter at x zero crossings from its center
+func resampleFilter(table []float64, x float64) float64 {
+	pos := math.Abs(x) * resampleTableSteps
+	i := int(pos)
+	if i >= len(table)-1 {
+		return 0
+	}
+	frac := pos - float64(i)
+	return table[i] + (table[i+1]-table[i])*frac
+}
+
+// maxResamplePhases is the most filter phases resample computes in
+// advance, one for each position of an output sample between two input
+// samples
+const maxResamplePhases = 1024
+
+// resample converts interleaved samples from one sample rate to another
+// with a windowed sinc filter. When downsampling the filter cuts off below
+// the new Nyquist frequency, so high tones don't fold back as noise. The
+// edges are filtered as if the sound were surrounded by silence.
+func resample(samples []float32, channels, from, to int) []float32 {
+	if from == to || len(samples) == 0 {
+		return samples
+	}
+	table := resampleTable()
+	frames := len(samples) / channels
+	outFrames := int(int64(frames) * int64(to) / int64(from))
+	cutoff := min(1, float64(to)/float64(from))
+	half := int(math.Ceil(resampleZeroCrossings / cutoff)) // in input frames
+
+	// Output sample i lies at input frame i*down/up; its fraction picks the
+	// filter phase
+	g := gcd(from, to)
+	up, down := to/g, from/g
+	weights := func(frac float64, w []float64) {
+		for j := range w {
+			w[j] = resampleFilter(table, (frac+float64(half-1-j))*cutoff) * cutoff
+		}
+	}
+	var bank [][]float64
+	if up <= maxResamplePhases {
+		bank = make([][]float64, up)
+		for phase := range bank {
+			bank[phase] = make([]float64, 2*half)
+			weights(float64(phase)/float64(up), bank[phase])
+		}
+	}
+
+	// Filter each channel on its own, which keeps the inner loop tight
+	out := make([]float32, outFrames*channels)
+	scratch := make([]float64, 2*half)
+	channel := make([]float32, frames)
+	for c := 0; c < channels; c++ {
+		for i := range channel {
+			channel[i] = samples[i*channels+c]
+		}
+		for i := 0; i < outFrames; i++ {
+			pos := int64(i) * int64(down)
+			center, phase := int(pos/int64(up)), int(pos%int64(up))
+			w := scratch
+			if bank != nil {
+				w = bank[phase]
+			} else {
+				weights(float64(phase)/float64(up), w)
+			}
+
+			first := center - half + 1
+			lo, hi := max(0, -first), min(len(w), frames-first)
+			var acc float64
+			for j, v := range channel[first+lo : first+hi] {
+				acc += w[lo+j] * float64(v)
+			}
+			out[i*channels+c] = float32(acc)
+		}
+	}
+	return out
+}
+
+// gcd returns the greatest common divisor of a and b
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// PCM converts the sound to the format of the audio context: resampled to
+// sampleRate, mixed to stereo and encoded as 16-bit little-endian. Sounds
+// with more than two channels are mixed first, mono is resampled first, so
+// the resampler handles as few channels as it can.
+func (s *Sound) PCM(sampleRate int) []byte {
+	sound := *s
+	if sound.Channels > 2 {
+		sound = Sound{Samples: s.Stereo(), SampleRate: s.SampleRate, Channels: 2}
+	}
+	sound.Samples = resample(sound.Samples, sound.Channels, sound.SampleRate, sampleRate)
+	samples := sound.Stereo()
+	buf := make([]byte, len(samples)*2)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint16(buf[i*2:], uint16(pcm16(v)))
+	}
+	return buf
+}
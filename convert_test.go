@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+)
+
+// TestMaskLayout tests reading speakers from WAV channel masks
+func TestMaskLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		mask     uint32
+		channels int
+		expected []Speaker
+	}{
+		{"stereo", 0x3, 2, []Speaker{FrontLeft, FrontRight}},
+		{"quad with center and LFE", 0xF, 4, []Speaker{FrontLeft, FrontRight, FrontCenter, LowFrequency}},
+		{"5.1 side", 0x60F, 6, []Speaker{FrontLeft, FrontRight, FrontCenter, LowFrequency, SideLeft, SideRight}},
+		{"count mismatch", 0x3, 3, nil},
+		{"unknown speaker", 0x1003, 3, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := maskLayout(tt.mask, tt.channels); !slices.Equal(result, tt.expected) {
+				t.Errorf("maskLayout(%#x, %d) = %v, want %v", tt.mask, tt.channels, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestStereo tests mixing channels to stereo
+func TestStereo(t *testing.T) {
+	// Normalized gains of a 5.1 mix: 1 and 0.707 twice per side
+	front, center := float32(1/(1+math.Sqrt2)), float32(math.Sqrt2/2/(1+math.Sqrt2))
+
+	tests := []struct {
+		name     string
+		sound    Sound
+		expected []float32
+	}{
+		{"mono on both sides", Sound{Samples: []float32{0.5, -1}, Channels: 1}, []float32{0.5, 0.5, -1, -1}},
+		{"stereo unchanged", Sound{Samples: []float32{0.5, -1}, Channels: 2}, []float32{0.5, -1}},
+		{"5.1 WAV order", Sound{Samples: []float32{1, 0, 0, 1, 0, 0}, Channels: 6}, []float32{front, 0}},
+		{"5.1 center", Sound{Samples: []float32{0, 0, 1, 0, 0, 0}, Channels: 6}, []float32{center, center}},
+		{"5.1 Vorbis order", Sound{Samples: []float32{0, 0, 1, 0, 0, 1}, Channels: 6, Layout: vorbisLayouts[6]}, []float32{0, front}},
+		{"unknown layout alternates", Sound{Samples: []float32{1, 0, 0, 0, 0, 0, 0, 0, 0}, Channels: 9}, []float32{0.2, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.sound.Stereo()
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Stereo() = %v, want %v", result, tt.expected)
+			}
+			for i := range result {
+				if math.Abs(float64(result[i]-tt.expected[i])) > 1e-6 {
+					t.Fatalf("Stereo() = %v, want %v", result, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+// sine returns a mono sine tone
+func sine(freq float64, sampleRate, frames int) []float32 {
+	samples := make([]float32, frames)
+	for i := range samples {
+		samples[i] = float32(0.5 * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)))
+	}
+	return samples
+}
+
+// TestResample tests converting sample rates
+func TestResample(t *testing.T) {
+	tests := []struct {
+		name     string
+		freq     float64
+		from, to int
+		// the output away from the edges is the tone at the new rate, or
+		// silence when it is above the new Nyquist frequency
+		silent bool
+	}{
+		{"up", 1000, 24000, 48000, false},
+		{"up uneven", 440, 22050, 48000, false},
+		{"down", 1000, 48000, 44100, false},
+		{"down filters high tones", 15000, 48000, 22050, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := resample(sine(tt.freq, tt.from, tt.from/10), 1, tt.from, tt.to)
+			if len(result) != tt.to/10 {
+				t.Fatalf("resample() length = %d, want %d", len(result), tt.to/10)
+			}
+			expected := sine(tt.freq, tt.to, len(result))
+			var maxErr float64
+			for i := len(result) / 4; i < len(result)*3/4; i++ {
+				want := float64(expected[i])
+				if tt.silent {
+					want = 0
+				}
+				maxErr = max(maxErr, math.Abs(float64(result[i])-want))
+			}
+			if maxErr > 0.01 {
+				t.Errorf("resample() differs by up to %.4f", maxErr)
+			}
+		})
+	}
+
+	same := []float32{1, 2, 3}
+	if result := resample(same, 1, 8000, 8000); &result[0] != &same[0] {
+		t.Error("resample() copied samples at the same rate")
+	}
+}
+
+// TestSoundPCM tests converting sounds to the format of the audio context
+func TestSoundPCM(t *testing.T) {
+	tests := []struct {
+		name       string
+		sound      Sound
+		sampleRate int
+		expected   []int16
+	}{
+		{"mono to stereo", Sound{Samples: []float32{0.5, -0.25}, SampleRate: 8000, Channels: 1}, 8000, []int16{16384, 16384, -8192, -8192}},
+		{"clipped", Sound{Samples: []float32{1, -1.5}, SampleRate: 8000, Channels: 1}, 8000, []int16{32767, 32767, -32768, -32768}},
+		{"empty", Sound{SampleRate: 8000, Channels: 1}, 8000, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pcm := tt.sound.PCM(tt.sampleRate)
+			var samples []int16
+			if len(pcm) > 0 {
+				samples = make([]int16, len(pcm)/2)
+				binary.Read(bytes.NewReader(pcm), binary.LittleEndian, samples)
+			}
+			if !slices.Equal(samples, tt.expected) {
+				t.Errorf("PCM() = %v, want %v", samples, tt.expected)
+			}
+		})
+	}
+
+	// A second of the built-in beep's rate is a second at 48 kHz
+	sound := Sound{Samples: make([]float32, 24000), SampleRate: 24000, Channels: 1}
+	if frames := len(sound.PCM(48000)) / 4; frames != 48000 {
+		t.Errorf("PCM() = %d frames, want 48000", frames)
+	}
+}
+
+// TestOutputSampleRate tests choosing the rate of the audio context
+func TestOutputSampleRate(t *testing.T) {
+	tests := []struct {
+		beepRate int
+		expected int
+	}{
+		{24000, 48000},
+		{44100, 44100},
+		{48000, 48000},
+		{96000, 96000},
+		{192000, 48000},
+	}
+
+	for _, tt := range tests {
+		if result := outputSampleRate(tt.beepRate); result != tt.expected {
+			t.Errorf("outputSampleRate(%d) = %d, want %d", tt.beepRate, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchLimit bounds the search for the next match, so expressions that
+// never match (e.g. "0 0 30 2 *") end the timer instead of looping forever
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// cronDescriptors are the supported shorthands for common expressions
+var cronDescriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+var (
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// cronField describes the range and names of one cron field
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names []string // names for min, min+1, ...
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, monthNames},
+	{"day of week", 0, 7, dayNames}, // 7 is Sunday as well
+}
+
+// CronSchedule beeps at the times matched by a standard five-field cron
+// expression (minute, hour, day of month, month, day of week) in its
+// location
+type CronSchedule struct {
+	Expr     string
+	Location *time.Location
+	minute   uint64 // bit n set if the field matches n
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	domStar  bool // day of month is "*"
+	dowStar  bool // day of week is "*"
+}
+
+// ParseCron parses a cron expression such as "*/20 9-17 * * 1-5". Fields
+// accept *, numbers, ranges (a-b), steps (*/n, a-b/n), lists (a,b) and
+// month and day names (jan, mon).
+func ParseCron(expr string, loc *time.Location) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 1 {
+		if expanded, ok := cronDescriptors[strings.ToLower(fields[0])]; ok {
+			fields = strings.Fields(expanded)
+		}
+	}
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: want 5 fields (minute hour day month weekday), got %d", expr, len(fields))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		b, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+		bits[i] = b
+	}
+	// Sunday can be written as 0 or 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &CronSchedule{
+		Expr:     strings.Join(strings.Fields(expr), " "),
+		Location: loc,
+		minute:   bits[0],
+		hour:     bits[1],
+		dom:      bits[2],
+		month:    bits[3],
+		dow:      bits[4],
+		domStar:  strings.HasPrefix(fields[2], "*"),
+		dowStar:  strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField returns the values matched by one field as a bit set
+func parseCronField(s string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepPart, f.name)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+			if f.name == "day of week" {
+				hi = 6
+			}
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = cronValue(a, f); err != nil {
+				return 0, err
+			}
+			if hi, err = cronValue(b, f); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangePart, f.name)
+			}
+		default:
+			v, err := cronValue(rangePart, f)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			if !hasStep {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// cronValue parses a number or name within the field's range
+func cronValue(s string, f cronField) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q in %s field (want %d-%d)", s, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first matching minute after t, or the zero time if the
+// expression never matches
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.In(c.Location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		switch {
+		case c.month&(1<<uint(m)) == 0:
+			t = c.advance(t, time.Date(y, m+1, 1, 0, 0, 0, 0, c.Location))
+		case !c.dayMatches(t):
+			t = c.advance(t, time.Date(y, m, d+1, 0, 0, 0, 0, c.Location))
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = c.advance(t, time.Date(y, m, d, t.Hour()+1, 0, 0, 0, c.Location))
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// advance moves to next, or by at least a minute if the clocks going back
+// would return to an earlier time
+func (c *CronSchedule) advance(t, next time.Time) time.Time {
+	if !next.After(t) {
+		return t.Add(time.Minute)
+	}
+	return next
+}
+
+// dayMatches applies the cron rule that a day matches either the day of
+// month or the day of week when both are restricted
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Describe returns a short description for tooltips and the verbose banner
+func (c *CronSchedule) Describe() string {
+	return fmt.Sprintf("Beeping at %s", c.Expr)
+}
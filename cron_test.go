@@ -0,0 +1,144 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseCron tests parsing and rejecting cron expressions
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr        string
+		expectError bool
+	}{
+		{"*/20 9-17 * * 1-5", false},
+		{"0,30 * * * *", false},
+		{"15 8 1 jan-mar MON", false},
+		{"5/15 * * * 7", false},
+		{"@hourly", false},
+		{"* * * *", true},
+		{"60 * * * *", true},
+		{"*/0 * * * *", true},
+		{"10-5 * * * *", true},
+		{"0 0 * * funday", true},
+		{"@sometimes", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseCron(tt.expr, time.UTC)
+			if tt.expectError && err == nil {
+				t.Errorf("ParseCron(%q) expected error, got nil", tt.expr)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("ParseCron(%q) unexpected error: %v", tt.expr, err)
+			}
+		})
+	}
+}
+
+// TestCronScheduleNext tests the Next method
+func TestCronScheduleNext(t *testing.T) {
+	// Sunday, October 18 2026
+	sunday := time.Date(2026, 10, 18, 11, 47, 30, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expr     string
+		from     time.Time
+		expected []time.Time
+	}{
+		{
+			name: "every 20 minutes on weekdays",
+			expr: "*/20 9-17 * * 1-5",
+			from: sunday,
+			expected: []time.Time{
+				time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 10, 19, 9, 20, 0, 0, time.UTC),
+				time.Date(2026, 10, 19, 9, 40, 0, 0, time.UTC),
+				time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "end of working day",
+			expr: "*/20 9-17 * * 1-5",
+			from: time.Date(2026, 10, 23, 17, 45, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2026, 10, 26, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "starts after the current minute",
+			expr: "* * * * *",
+			from: sunday,
+			expected: []time.Time{
+				time.Date(2026, 10, 18, 11, 48, 0, 0, time.UTC),
+				time.Date(2026, 10, 18, 11, 49, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "day of month or day of week",
+			expr: "0 12 1 * sun",
+			from: sunday,
+			expected: []time.Time{
+				time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+				time.Date(2026, 10, 25, 12, 0, 0, 0, time.UTC),
+				time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "leap day",
+			expr: "0 0 29 2 *",
+			from: sunday,
+			expected: []time.Time{
+				time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "never",
+			expr:     "0 0 30 2 *",
+			from:     sunday,
+			expected: []time.Time{{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			at := tt.from
+			for i, want := range tt.expected {
+				next := cron.Next(at)
+				if !next.Equal(want) {
+					t.Fatalf("beep %d = %v, want %v", i+1, next, want)
+				}
+				at = next
+			}
+		})
+	}
+}
+
+// TestCronScheduleNextDST tests cron times around daylight saving changes
+func TestCronScheduleNextDST(t *testing.T) {
+	loc := loadLocation(t, "Europe/Berlin")
+	cron, err := ParseCron("30 2 * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 02:30 doesn't exist on March 29 2026, the next beep is the day after
+	next := cron.Next(time.Date(2026, 3, 29, 0, 0, 0, 0, loc))
+	if want := time.Date(2026, 3, 30, 2, 30, 0, 0, loc); !next.Equal(want) {
+		t.Errorf("Next() = %v, want %v", next, want)
+	}
+
+	// Daily times stay at the same wall-clock time after the clocks go back
+	cron, _ = ParseCron("0 9 * * *", loc)
+	next = cron.Next(time.Date(2026, 10, 24, 12, 0, 0, 0, loc))
+	next = cron.Next(next)
+	if want := time.Date(2026, 10, 26, 9, 0, 0, 0, loc); !next.Equal(want) {
+		t.Errorf("Next() = %v, want %v", next, want)
+	}
+}
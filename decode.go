@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/jfreymuth/oggvorbis"
+)
+
+// soundExtensions are the file extensions of the formats bleep can play
+var soundExtensions = []string{".wav", ".ogg", ".flac", ".mp3"}
+
+// Sound is decoded audio: interleaved samples from -1 to 1 at the sample
+// rate and channel count of the file
+type Sound struct {
+	Samples    []float32
+	SampleRate int
+	Channels   int
+	Layout     []Speaker // the speaker of each channel, nil for the default order
+}
+
+// Frames returns the number of samples per channel
+func (s *Sound) Frames() int {
+	return len(s.Samples) / s.Channels
+}
+
+// Decoder decodes one audio file format
+type Decoder interface {
+	// Format names the format in errors
+	Format() string
+	// Sniff reports whether a file starts like one of the format
+	Sniff(header []byte) bool
+	// Decode decodes the whole file
+	Decode(data []byte) (*Sound, error)
+}
+
+// decoders are tried in order on the start of a file. MP3 goes last: files
+// without an ID3 tag are only recognised by a frame sync.
+var decoders = []Decoder{wavDecoder{}, vorbisDecoder{}, flacDecoder{}, mp3Decoder{}}
+
+// DecodeSound decodes a WAV, OGG Vorbis, FLAC or MP3 file, telling the format
+// by its header
+func DecodeSound(data []byte) (*Sound, error) {
+	for _, decoder := range decoders {
+		if !decoder.Sniff(data) {
+			continue
+		}
+		sound, err := decoder.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", decoder.Format(), err)
+		}
+		switch {
+		case sound.SampleRate <= 0:
+			return nil, fmt.Errorf("%s: invalid sample rate %d Hz", decoder.Format(), sound.SampleRate)
+		case sound.Channels <= 0:
+			return nil, fmt.Errorf("%s: no channels", decoder.Format())
+		}
+		sound.Samples = sound.Samples[:sound.Frames()*sound.Channels]
+		return sound, nil
+	}
+	return nil, errors.New("unknown audio format, want WAV, OGG Vorbis, FLAC or MP3")
+}
+
+// intSample scales a signed integer sample of the given bit depth to -1..1
+func intSample(v int64, bits int) float32 {
+	return float32(float64(v) / float64(int64(1)<<(bits-1)))
+}
+
+// pcm16 converts a sample to 16 bits, clipping it to the range; the inverse
+// of intSample
+func pcm16(v float32) int16 {
+	return int16(max(math.MinInt16, min(math.MaxInt16, math.Round(float64(v)*(1<<15)))))
+}
+
+// mp3Decoder decodes MPEG audio layer III, the format of the built-in beep
+type mp3Decoder struct{}
+
+func (mp3Decoder) Format() string { return "MP3" }
+
+func (mp3Decoder) Sniff(header []byte) bool {
+	return bytes.HasPrefix(header, []byte("ID3")) || len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0
+}
+
+// Decode decodes the file with go-mp3, which always outputs 16-bit stereo
+func (mp3Decoder) Decode(data []byte) (*Sound, error) {
+	decoder, err := mp3.NewDecoder(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	pcm, err := io.ReadAll(decoder)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]float32, len(pcm)/2)
+	for i := range samples {
+		samples[i] = intSample(int64(int16(binary.LittleEndian.Uint16(pcm[i*2:]))), 16)
+	}
+	return &Sound{Samples: samples, SampleRate: decoder.SampleRate(), Channels: 2}, nil
+}
+
+// vorbisDecoder decodes Vorbis audio in an Ogg container
+type vorbisDecoder struct{}
+
+func (vorbisDecoder) Format() string { return "OGG Vorbis" }
+
+func (vorbisDecoder) Sniff(header []byte) bool {
+	return bytes.HasPrefix(header, []byte("OggS"))
+}
+
+func (vorbisDecoder) Decode(data []byte) (*Sound, error) {
+	samples, format, err := oggvorbis.ReadAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return &Sound{
+		Samples:    samples,
+		SampleRate: format.SampleRate,
+		Channels:   format.Channels,
+		Layout:     vorbisLayouts[format.Channels],
+	}, nil
+}
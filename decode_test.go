@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// TestDecodeSound tests telling the format of a file by its header
+func TestDecodeSound(t *testing.T) {
+	ogg, err := os.ReadFile("testdata/test.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flac, err := os.ReadFile("testdata/test.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wav, err := os.ReadFile("testdata/pluck24.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		sampleRate int
+		channels   int
+		frames     int
+		wantErr    string
+	}{
+		{"wav", wavFile(1, 8000, 1, 2, 3), 8000, 1, 3, ""},
+		{"flac", flacFile(0, verbatimSubframe, []int64{1, 2}), 8000, 1, 2, ""},
+		{"wav file", wav, 11025, 2, 3307, ""},
+		{"flac file", flac, 8000, 1, 21751, ""},
+		{"ogg vorbis", ogg, 44100, 1, 44100, ""},
+		{"mp3", beepMP3, 24000, 2, -1, ""},
+		{"unknown", []byte("hello"), 0, 0, 0, "unknown audio format, want WAV, OGG Vorbis, FLAC or MP3"},
+		{"broken", []byte("RIFF\x04\x00\x00\x00WAVE"), 0, 0, 0, "WAV: missing fmt or data chunk"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sound, err := DecodeSound(tt.data)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("DecodeSound() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeSound() error = %v", err)
+			}
+			if sound.SampleRate != tt.sampleRate || sound.Channels != tt.channels {
+				t.Errorf("DecodeSound() format = %d Hz, %d channels, want %d Hz, %d channels",
+					sound.SampleRate, sound.Channels, tt.sampleRate, tt.channels)
+			}
+			if tt.frames >= 0 && sound.Frames() != tt.frames || sound.Frames() == 0 {
+				t.Errorf("Frames() = %d, want %d", sound.Frames(), tt.frames)
+			}
+		})
+	}
+}
+
+// TestSniff tests that each decoder only claims its own files
+func TestSniff(t *testing.T) {
+	headers := map[string][]byte{
+		"WAV":        wavFile(1, 8000),
+		"OGG Vorbis": []byte("OggS\x00\x02"),
+		"FLAC":       []byte("fLaC\x80"),
+		"MP3":        []byte("ID3\x04"),
+	}
+	for _, decoder := range decoders {
+		for format, header := range headers {
+			if got := decoder.Sniff(header); got != (format == decoder.Format()) {
+				t.Errorf("%s Sniff(%s header) = %v", decoder.Format(), format, got)
+			}
+		}
+	}
+	if !(mp3Decoder{}).Sniff([]byte{0xFF, 0xFB, 0x90}) {
+		t.Error("MP3 Sniff() = false for a frame sync without ID3 tag")
+	}
+	if !(mp3Decoder{}).Sniff(beepMP3) {
+		t.Error("MP3 Sniff() = false for the built-in beep")
+	}
+}
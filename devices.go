@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Audio device kinds
+const (
+	DeviceALSA  = "alsa"  // a playback device of a sound card
+	DevicePulse = "pulse" // a PulseAudio or PipeWire sink
+)
+
+// alsaConfigPaths are where alsa-lib's top-level configuration is usually
+// installed
+var alsaConfigPaths = []string{"/usr/share/alsa/alsa.conf", "/usr/local/share/alsa/alsa.conf"}
+
+// AudioDevice is an audio output that -device can select
+type AudioDevice struct {
+	Name        string // the -device value
+	Description string
+	Kind        string
+	Card        string // ALSA only: id and number of the sound card
+	CardNumber  int
+}
+
+// listDevices returns the playback devices of the sound cards and the sinks
+// of a running PulseAudio or PipeWire server
+func listDevices() []AudioDevice {
+	devices := alsaDevices(os.DirFS("/proc/asound"))
+	if out, err := exec.Command("pactl", "list", "short", "sinks").Output(); err == nil {
+		devices = append(devices, parsePulseSinks(string(out))...)
+	}
+	return devices
+}
+
+// cardLine matches a card in /proc/asound/cards, e.g.
+// " 0 [PCH            ]: HDA-Intel - HDA Intel PCH"
+var cardLine = regexp.MustCompile(`^\s*(\d+)\s+\[(\S+)\s*\]:\s*(.*)$`)
+
+// alsaDevices reads the playback devices from /proc/asound: cards names the
+// sound cards, pcm lists their devices. The plughw names convert the sample
+// format and rate, which raw hw devices may not support.
+func alsaDevices(fsys fs.FS) []AudioDevice {
+	cardsFile, err := fs.ReadFile(fsys, "cards")
+	if err != nil {
+		return nil
+	}
+	pcmFile, err := fs.ReadFile(fsys, "pcm")
+	if err != nil {
+		return nil
+	}
+
+	type card struct{ id, name string }
+	cards := make(map[int]card)
+	for _, line := range strings.Split(string(cardsFile), "\n") {
+		m := cardLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		number, _ := strconv.Atoi(m[1])
+		name := m[3]
+		if _, after, ok := strings.Cut(name, " - "); ok {
+			name = after
+		}
+		cards[number] = card{m[2], name}
+	}
+
+	// e.g. "00-00: ALC257 Analog : ALC257 Analog : playback 1 : capture 1"
+	var devices []AudioDevice
+	for _, line := range strings.Split(string(pcmFile), "\n") {
+		fields := strings.Split(line, " : ")
+		if !slices.ContainsFunc(fields, func(f string) bool { return strings.HasPrefix(f, "playback") }) {
+			continue
+		}
+		var number, dev int
+		if _, err := fmt.Sscanf(fields[0], "%d-%d:", &number, &dev); err != nil {
+			continue
+		}
+		c, ok := cards[number]
+		if !ok {
+			continue
+		}
+		description := c.name
+		if len(fields) > 1 {
+			description += ", " + strings.TrimSpace(fields[1])
+		}
+		devices = append(devices, AudioDevice{
+			Name:        fmt.Sprintf("plughw:CARD=%s,DEV=%d", c.id, dev),
+			Description: description,
+			Kind:        DeviceALSA,
+			Card:        c.id,
+			CardNumber:  number,
+		})
+	}
+	return devices
+}
+
+// parsePulseSinks parses the output of `pactl list short sinks`: index,
+// name, driver, sample spec and state, separated by tabs
+func parsePulseSinks(out string) []AudioDevice {
+	var devices []AudioDevice
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || fields[1] == "" {
+			continue
+		}
+		description := "sink"
+		if len(fields) >= 4 {
+			description = fields[3]
+		}
+		devices = append(devices, AudioDevice{Name: fields[1], Description: description, Kind: DevicePulse})
+	}
+	return devices
+}
+
+// alsaCard returns the card an ALSA device name refers to, e.g. "PCH" for
+// "plughw:CARD=PCH,DEV=0" or "1" for "hw:1,0"
+func alsaCard(name string) string {
+	_, args, ok := strings.Cut(name, ":")
+	if !ok {
+		return ""
+	}
+	card, _, _ := strings.Cut(args, ",")
+	return strings.TrimPrefix(card, "CARD=")
+}
+
+// findDevice looks up a -device value: a listed device, or another ALSA
+// device name on a listed sound card (e.g. "hw:1,0")
+func findDevice(name string, devices []AudioDevice) (AudioDevice, bool) {
+	for _, device := range devices {
+		if device.Name == name {
+			return device, true
+		}
+	}
+	card := alsaCard(name)
+	for _, device := range devices {
+		if device.Kind == DeviceALSA && card != "" && (card == device.Card || card == strconv.Itoa(device.CardNumber)) {
+			return AudioDevice{Name: name, Kind: DeviceALSA, Card: device.Card, CardNumber: device.CardNumber}, true
+		}
+	}
+	return AudioDevice{}, false
+}
+
+// selectDevice makes the audio context, which always opens ALSA's default
+// device, play on the named device instead. For PulseAudio and PipeWire sinks
+// it sets the sink their ALSA plugins connect to, for ALSA devices it adds a
+// configuration file overriding the default device. The returned function
+// removes that file once the device is open. On error the default device is
+// used.
+func selectDevice(name string, devices []AudioDevice) (func(), error) {
+	noop := func() {}
+	device, ok := findDevice(name, devices)
+	if !ok {
+		return noop, fmt.Errorf("no audio device %q, see bleep devices", name)
+	}
+
+	if device.Kind == DevicePulse {
+		os.Setenv("PULSE_SINK", device.Name)
+		os.Setenv("PIPEWIRE_NODE", device.Name)
+		return noop, nil
+	}
+
+	configPath := os.Getenv("ALSA_CONFIG_PATH")
+	if configPath == "" {
+		for _, path := range alsaConfigPaths {
+			if _, err := os.Stat(path); err == nil {
+				configPath = path
+				break
+			}
+		}
+	}
+	if configPath == "" {
+		return noop, errors.New("ALSA configuration not found")
+	}
+	f, err := os.CreateTemp("", "bleep-alsa-*.conf")
+	if err != nil {
+		return noop, err
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "pcm.!default %q\n", device.Name); err != nil {
+		os.Remove(f.Name())
+		return noop, err
+	}
+	os.Setenv("ALSA_CONFIG_PATH", configPath+":"+f.Name())
+	return func() { os.Remove(f.Name()) }, nil
+}
+
+// runDevices implements `bleep devices`
+func runDevices(stdout io.Writer) int {
+	printDevices(stdout, listDevices())
+	return 0
+}
+
+// printDevices lists the devices by kind
+func printDevices(stdout io.Writer, devices []AudioDevice) {
+	if len(devices) == 0 {
+		fmt.Fprintln(stdout, "No audio devices found, bleep plays on the system default.")
+		return
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	for _, kind := range []string{DeviceALSA, DevicePulse} {
+		first := true
+		for _, device := range devices {
+			if device.Kind != kind {
+				continue
+			}
+			if first {
+				if kind == DeviceALSA {
+					fmt.Fprintln(tw, "ALSA devices:")
+				} else {
+					fmt.Fprintln(tw, "PulseAudio/PipeWire sinks:")
+				}
+				first = false
+			}
+			fmt.Fprintf(tw, "  %s\t%s\n", device.Name, device.Description)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintln(stdout, "\nPlay the beep on one with -device <name>.")
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// procAsound is a /proc/asound with a laptop's card and a USB headset
+var procAsound = fstest.MapFS{
+	"cards": {Data: []byte(` 0 [PCH            ]: HDA-Intel - HDA Intel PCH
+                      HDA Intel PCH at 0xea310000 irq 147
+ 1 [Headset        ]: USB-Audio - USB Headset
+                      Logitech USB Headset at usb-0000:00:14.0-2, full speed
+`)},
+	"pcm": {Data: []byte(`00-00: ALC257 Analog : ALC257 Analog : playback 1 : capture 1
+00-03: HDMI 0 : HDMI 0 : playback 1
+00-07: DMIC : DMIC : capture 1
+01-00: USB Audio : USB Audio : playback 1 : capture 1
+`)},
+}
+
+// TestAlsaDevices tests reading the playback devices from /proc/asound
+func TestAlsaDevices(t *testing.T) {
+	devices := alsaDevices(procAsound)
+	expected := []AudioDevice{
+		{"plughw:CARD=PCH,DEV=0", "HDA Intel PCH, ALC257 Analog", DeviceALSA, "PCH", 0},
+		{"plughw:CARD=PCH,DEV=3", "HDA Intel PCH, HDMI 0", DeviceALSA, "PCH", 0},
+		{"plughw:CARD=Headset,DEV=0", "USB Headset, USB Audio", DeviceALSA, "Headset", 1},
+	}
+	if len(devices) != len(expected) {
+		t.Fatalf("alsaDevices() = %v, want %v", devices, expected)
+	}
+	for i := range expected {
+		if devices[i] != expected[i] {
+			t.Errorf("device %d = %+v, want %+v", i, devices[i], expected[i])
+		}
+	}
+
+	if devices := alsaDevices(fstest.MapFS{}); devices != nil {
+		t.Errorf("alsaDevices() without sound cards = %v, want none", devices)
+	}
+}
+
+// TestParsePulseSinks tests parsing pactl output
+func TestParsePulseSinks(t *testing.T) {
+	out := "47\talsa_output.pci-0000_00_1f.3.analog-stereo\tPipeWire\ts32le 2ch 48000Hz\tSUSPENDED\n" +
+		"52\tbluez_output.AC_80_0A_2E_81_6A.1\tPipeWire\ts16le 2ch 48000Hz\tRUNNING\n"
+	devices := parsePulseSinks(out)
+	if len(devices) != 2 {
+		t.Fatalf("parsePulseSinks() = %v, want 2 sinks", devices)
+	}
+	expected := AudioDevice{Name: "bluez_output.AC_80_0A_2E_81_6A.1", Description: "s16le 2ch 48000Hz", Kind: DevicePulse}
+	if devices[1] != expected {
+		t.Errorf("sink = %+v, want %+v", devices[1], expected)
+	}
+}
+
+// TestFindDevice tests looking up -device values
+func TestFindDevice(t *testing.T) {
+	devices := append(alsaDevices(procAsound), AudioDevice{Name: "headphones", Kind: DevicePulse})
+	tests := []struct {
+		name  string
+		found bool
+		kind  string
+	}{
+		{"plughw:CARD=Headset,DEV=0", true, DeviceALSA},
+		{"hw:1,0", true, DeviceALSA},
+		{"sysdefault:CARD=PCH", true, DeviceALSA},
+		{"headphones", true, DevicePulse},
+		{"hw:2,0", false, ""},
+		{"speakers", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device, found := findDevice(tt.name, devices)
+			if found != tt.found || device.Kind != tt.kind {
+				t.Errorf("findDevice(%q) = %+v, %v, want kind %q, %v", tt.name, device, found, tt.kind, tt.found)
+			}
+		})
+	}
+}
+
+// TestSelectDevice tests pointing the audio context at a device
+func TestSelectDevice(t *testing.T) {
+	devices := append(alsaDevices(procAsound), AudioDevice{Name: "headphones", Kind: DevicePulse})
+	t.Setenv("PULSE_SINK", "")
+	t.Setenv("PIPEWIRE_NODE", "")
+	t.Setenv("ALSA_CONFIG_PATH", "")
+	alsaConf := filepath.Join(t.TempDir(), "alsa.conf")
+	os.WriteFile(alsaConf, nil, 0o644)
+	saved := alsaConfigPaths
+	alsaConfigPaths = []string{filepath.Join(t.TempDir(), "missing.conf"), alsaConf}
+	defer func() { alsaConfigPaths = saved }()
+
+	t.Run("sink", func(t *testing.T) {
+		if _, err := selectDevice("headphones", devices); err != nil {
+			t.Fatalf("selectDevice() error = %v", err)
+		}
+		if sink := os.Getenv("PULSE_SINK"); sink != "headphones" {
+			t.Errorf("PULSE_SINK = %q, want headphones", sink)
+		}
+	})
+
+	t.Run("alsa", func(t *testing.T) {
+		opened, err := selectDevice("hw:1,0", devices)
+		if err != nil {
+			t.Fatalf("selectDevice() error = %v", err)
+		}
+		configPath := os.Getenv("ALSA_CONFIG_PATH")
+		base, override, ok := strings.Cut(configPath, ":")
+		if !ok || base != alsaConf {
+			t.Fatalf("ALSA_CONFIG_PATH = %q, want %s followed by the override", configPath, alsaConf)
+		}
+		if data, _ := os.ReadFile(override); string(data) != "pcm.!default \"hw:1,0\"\n" {
+			t.Errorf("override = %q", data)
+		}
+		opened()
+		if _, err := os.Stat(override); !os.IsNotExist(err) {
+			t.Errorf("override still exists after opening the device")
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		if _, err := selectDevice("speakers", devices); err == nil {
+			t.Error("selectDevice() succeeded for an unknown device")
+		}
+	})
+}
+
+// TestPrintDevices tests the bleep devices listing
+func TestPrintDevices(t *testing.T) {
+	var buf bytes.Buffer
+	printDevices(&buf, append(alsaDevices(procAsound)[:1], AudioDevice{Name: "headphones", Description: "s16le 2ch 48000Hz", Kind: DevicePulse}))
+	expected := `ALSA devices:
+  plughw:CARD=PCH,DEV=0  HDA Intel PCH, ALC257 Analog
+PulseAudio/PipeWire sinks:
+  headphones  s16le 2ch 48000Hz
+
+Play the beep on one with -device <name>.
+`
+	if buf.String() != expected {
+		t.Errorf("printDevices() = %q, want %q", buf.String(), expected)
+	}
+
+	buf.Reset()
+	printDevices(&buf, nil)
+	if !strings.HasPrefix(buf.String(), "No audio devices found") {
+		t.Errorf("printDevices() without devices = %q", buf.String())
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventSchemaVersion is the version of the -events schema. It is bumped
+// whenever a field is removed or changes meaning; new fields may be added
+// without a version change.
+const EventSchemaVersion = 1
+
+// EventType identifies what happened to the timer
+type EventType string
+
+const (
+	EventStart        EventType = "start"
+	EventTick         EventType = "tick"
+	EventBeep         EventType = "beep"
+	EventReset        EventType = "reset"
+	EventPause        EventType = "pause"
+	EventResume       EventType = "resume"
+	EventSkip         EventType = "skip"
+	EventSegmentStart EventType = "segment-start"
+	EventFinish       EventType = "finish"
+	EventStop         EventType = "stop"
+	EventLap          EventType = "lap"
+	EventClockJump    EventType = "clock-jump"
+	EventWarning      EventType = "warning"
+	EventAlarm        EventType = "alarm"
+	EventAcknowledge  EventType = "acknowledge"
+)
+
+// Event is one timer event, printed as a JSON line by -events
+type Event struct {
+	Version   int       `json:"version"`
+	Type      EventType `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	BeepType  string    `json:"beep_type,omitempty"`
+	Segment   int       `json:"segment"`
+	Segments  int       `json:"segments"`
+	Label     string    `json:"label"`
+	Remaining int       `json:"remaining"`
+	BeepCount int       `json:"beep_count"`
+	Paused    bool      `json:"paused"`
+	// Stopwatch mode only: time counted up, current lap and its time
+	Stopwatch bool `json:"stopwatch,omitempty"`
+	Elapsed   int  `json:"elapsed,omitempty"`
+	Lap       int  `json:"lap,omitempty"`
+	LapTime   int  `json:"lap_time,omitempty"`
+	// clock-jump only: seconds the wall clock moved beyond the time bleep
+	// was running (negative if it was set back), the -on-suspend policy and
+	// whether a beep fell into the jump
+	Jump   int    `json:"jump,omitempty"`
+	Policy string `json:"policy,omitempty"`
+	Missed bool   `json:"missed,omitempty"`
+	// Set while an -alarm is sounding; alarm and acknowledge events carry
+	// the number of alarm beeps played
+	Alarm      bool `json:"alarm,omitempty"`
+	AlarmBeeps int  `json:"alarm_beeps,omitempty"`
+	// finish and stop only: what the session did
+	Summary *Summary `json:"summary,omitempty"`
+}
+
+// NewEvent creates an event describing the current timer state
+func NewEvent(eventType EventType, config OutputConfig, ts *TimerState, timestamp time.Time) Event {
+	remaining := ts.Remaining()
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Event{
+		Version:   EventSchemaVersion,
+		Type:      eventType,
+		Timestamp: timestamp,
+		Segment:   ts.IntervalIndex + 1,
+		Segments:  len(ts.Intervals),
+		Label:     labelFor(config.Labels, ts.IntervalIndex),
+		Remaining: int(remaining.Round(time.Second).Seconds()),
+		BeepCount: ts.BeepCount,
+		Paused:    ts.Paused,
+	}
+}
+
+// FormatEventOutput returns the event as a single JSON line
+func FormatEventOutput(event Event) string {
+	jsonBytes, _ := json.Marshal(event)
+	return string(jsonBytes)
+}
+
+// secondsDuration converts the whole seconds of an event field to a duration
+func secondsDuration(seconds int) time.Duration {
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestNewEvent tests the NewEvent constructor
+func TestNewEvent(t *testing.T) {
+	intervals := []time.Duration{25 * time.Minute, 5 * time.Minute}
+	ts := NewTimerState(intervals, []int{25, 5}, []int{0, 0}, true)
+	ts.IntervalIndex = 1
+	ts.PausedAt = 4*time.Minute + 30*time.Second
+	ts.BeepCount = 3
+	config := OutputConfig{Labels: []string{"work", "break"}}
+	timestamp := time.Date(2024, 12, 13, 15, 30, 0, 0, time.UTC)
+
+	event := NewEvent(EventPause, config, ts, timestamp)
+
+	if event.Version != EventSchemaVersion {
+		t.Errorf("Version = %d, want %d", event.Version, EventSchemaVersion)
+	}
+	if event.Type != EventPause {
+		t.Errorf("Type = %q, want %q", event.Type, EventPause)
+	}
+	if event.Segment != 2 || event.Segments != 2 {
+		t.Errorf("Segment/Segments = %d/%d, want 2/2", event.Segment, event.Segments)
+	}
+	if event.Label != "break" {
+		t.Errorf("Label = %q, want %q", event.Label, "break")
+	}
+	if event.Remaining != 270 {
+		t.Errorf("Remaining = %d, want 270", event.Remaining)
+	}
+	if event.BeepCount != 3 {
+		t.Errorf("BeepCount = %d, want 3", event.BeepCount)
+	}
+	if !event.Paused {
+		t.Error("Paused = false, want true")
+	}
+}
+
+// TestFormatEventOutput tests the JSON schema of events
+func TestFormatEventOutput(t *testing.T) {
+	event := Event{
+		Version:   EventSchemaVersion,
+		Type:      EventBeep,
+		Timestamp: time.Date(2024, 12, 13, 15, 30, 0, 0, time.UTC),
+		BeepType:  "manual",
+		Segment:   1,
+		Segments:  2,
+		Label:     "work",
+		BeepCount: 1,
+	}
+
+	expected := `{"version":1,"type":"beep","timestamp":"2024-12-13T15:30:00Z","beep_type":"manual",` +
+		`"segment":1,"segments":2,"label":"work","remaining":0,"beep_count":1,"paused":false}`
+	if result := FormatEventOutput(event); result != expected {
+		t.Errorf("FormatEventOutput() =\n%s\nwant\n%s", result, expected)
+	}
+
+	t.Run("beep_type omitted for other events", func(t *testing.T) {
+		event.Type = EventTick
+		event.BeepType = ""
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(FormatEventOutput(event)), &fields); err != nil {
+			t.Fatalf("json.Unmarshal error: %v", err)
+		}
+		if _, ok := fields["beep_type"]; ok {
+			t.Error("beep_type should be omitted for tick events")
+		}
+	})
+}
+
+// TestFormatBeepOutputEventsMode tests that the events mode has no beep line
+func TestFormatBeepOutputEventsMode(t *testing.T) {
+	config := OutputConfig{
+		Mode:          ModeEvents,
+		MinutesList:   []int{25},
+		SecondsList:   []int{0},
+		IntervalCount: 1,
+	}
+	if result := FormatBeepOutput(config, 1, "automatic", 0, time.Now()); result != "" {
+		t.Errorf("expected empty string, got %q", result)
+	}
+}
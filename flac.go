@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// flacDecoder decodes FLAC files. It reads the stream info and the audio
+// frames and skips the other metadata. Only the CRC-16 of each frame is
+// checked, not the header CRC-8 or the MD5 of the stream.
+type flacDecoder struct{}
+
+func (flacDecoder) Format() string { return "FLAC" }
+
+func (flacDecoder) Sniff(header []byte) bool {
+	return bytes.HasPrefix(header, []byte("fLaC"))
+}
+
+// flacStreamInfo is the part of the STREAMINFO block needed for decoding
+type flacStreamInfo struct {
+	sampleRate int
+	channels   int
+	bits       int
+}
+
+func (d flacDecoder) Decode(data []byte) (*Sound, error) {
+	if !d.Sniff(data) {
+		return nil, errors.New("not a FLAC file")
+	}
+
+	var info flacStreamInfo
+	pos := 4
+	for last := false; !last; {
+		if pos+4 > len(data) {
+			return nil, errors.New("truncated metadata")
+		}
+		last = data[pos]&0x80 != 0
+		kind := data[pos] & 0x7F
+		size := int(data[pos+1])<<16 | int(data[pos+2])<<8 | int(data[pos+3])
+		pos += 4
+		if pos+size > len(data) {
+			return nil, errors.New("truncated metadata")
+		}
+		if kind == 0 {
+			if size < 18 {
+				return nil, errors.New("short STREAMINFO block")
+			}
+			// 20 bits sample rate, 3 bits channels - 1, 5 bits bits per
+			// sample - 1, after the block and frame sizes
+			v := binary.BigEndian.Uint32(data[pos+10:])
+			info = flacStreamInfo{
+				sampleRate: int(v >> 12),
+				channels:   int(v>>9&0x7) + 1,
+				bits:       int(v>>4&0x1F) + 1,
+			}
+		}
+		pos += size
+	}
+	if info.sampleRate == 0 {
+		return nil, errors.New("missing STREAMINFO block")
+	}
+
+	sound := &Sound{SampleRate: info.sampleRate, Channels: info.channels}
+	r := &bitReader{data: data, pos: pos * 8}
+	for r.pos/8+2 <= len(data) {
+		samples, err := decodeFLACFrame(r, info)
+		if err != nil {
+			return nil, fmt.Errorf("frame at byte %d: %w", r.pos/8, err)
+		}
+		sound.Samples = append(sound.Samples, samples...)
+	}
+	return sound, nil
+}
+
+// FLAC channel assignments above the independent ones, where one channel
+// holds the difference of left and right
+const (
+	flacLeftSide  = 8
+	flacSideRight = 9
+	flacMidSide   = 10
+)
+
+// decodeFLACFrame decodes one frame into interleaved samples
+func decodeFLACFrame(r *bitReader, info flacStreamInfo) ([]float32, error) {
+	start := r.pos / 8
+	if sync := r.read(14); sync != 0x3FFE {
+		return nil, errors.New("missing frame sync")
+	}
+	r.read(2) // reserved, blocking strategy
+	blockCode := r.read(4)
+	rateCode := r.read(4)
+	assignment := int(r.read(4))
+	bitsCode := r.read(3)
+	r.read(1)
+
+	// The coded frame or sample number: UTF-8 style, the leading ones of the
+	// first byte give the number of bytes
+	first := r.read(8)
+	for mask := uint64(0x40); first&0x80 != 0 && first&mask != 0; mask >>= 1 {
+		r.read(8)
+	}
+
+	var blockSize int
+	switch {
+	case blockCode == 0:
+		return nil, errors.New("reserved block size")
+	case blockCode == 1:
+		blockSize = 192
+	case blockCode <= 5:
+		blockSize = 576 << (blockCode - 2)
+	case blockCode == 6:
+		blockSize = int(r.read(8)) + 1
+	case blockCode == 7:
+		blockSize = int(r.read(16)) + 1
+	default:
+		blockSize = 256 << (blockCode - 8)
+	}
+	switch rateCode {
+	case 12:
+		r.read(8)
+	case 13, 14:
+		r.read(16)
+	case 15:
+		return nil, errors.New("invalid sample rate")
+	}
+
+	bits := info.bits
+	if bitsCode != 0 {
+		sizes := []int{0, 8, 12, 0, 16, 20, 24, 32}
+		if bits = sizes[bitsCode]; bits == 0 {
+			return nil, errors.New("reserved sample size")
+		}
+	}
+	channels := assignment + 1
+	if assignment >= flacLeftSide {
+		if assignment > flacMidSide {
+			return nil, fmt.Errorf("reserved channel assignment %d", assignment)
+		}
+		channels = 2
+	}
+	if channels != info.channels {
+		return nil, fmt.Errorf("%d channels, the stream has %d", channels, info.channels)
+	}
+	r.read(8) // CRC-8 of the header
+
+	subframes := make([][]int64, channels)
+	for c := range subframes {
+		// The side channel has an extra bit
+		subBits := bits
+		if assignment == flacLeftSide && c == 1 || assignment == flacSideRight && c == 0 || assignment == flacMidSide && c == 1 {
+			subBits++
+		}
+		samples, err := decodeFLACSubframe(r, blockSize, subBits)
+		if err != nil {
+			return nil, fmt.Errorf("channel %d: %w", c, err)
+		}
+		subframes[c] = samples
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	switch assignment {
+	case flacLeftSide:
+		for i, side := range subframes[1] {
+			subframes[1][i] = subframes[0][i] - side
+		}
+	case flacSideRight:
+		for i, side := range subframes[0] {
+			subframes[0][i] = side + subframes[1][i]
+		}
+	case flacMidSide:
+		for i, side := range subframes[1] {
+			mid := subframes[0][i]<<1 | side&1
+			subframes[0][i] = (mid + side) >> 1
+			subframes[1][i] = (mid - side) >> 1
+		}
+	}
+
+	// Padding to the byte boundary, then the CRC-16 of the frame
+	r.align()
+	crc := flacCRC16(r.data[start:min(r.pos/8, len(r.data))])
+	if v := r.read(16); r.err == nil && v != uint64(crc) {
+		return nil, errors.New("CRC-16 mismatch")
+	}
+
+	out := make([]float32, blockSize*channels)
+	for c, samples := range subframes {
+		for i, v := range samples {
+			out[i*channels+c] = intSample(v, bits)
+		}
+	}
+	return out, r.err
+}
+
+// decodeFLACSubframe decodes the samples of one channel
+func decodeFLACSubframe(r *bitReader, blockSize, bits int) ([]int64, error) {
+	r.read(1)
+	kind := int(r.read(6))
+	wasted := 0
+	if r.read(1) == 1 {
+		wasted = r.unary() + 1
+		bits -= wasted
+	}
+
+	samples := make([]int64, blockSize)
+	switch {
+	case kind == 0: // CONSTANT
+		v := r.signed(bits)
+		for i := range samples {
+			samples[i] = v
+		}
+	case kind == 1: // VERBATIM
+		for i := range samples {
+			samples[i] = r.signed(bits)
+		}
+	case kind >= 8 && kind <= 12: // FIXED
+		order := kind - 8
+		if order > blockSize {
+			return nil, errors.New("predictor order above block size")
+		}
+		for i := 0; i < order; i++ {
+			samples[i] = r.signed(bits)
+		}
+		if err := decodeFLACPrediction(r, samples, fixedCoefficients[order], 0); err != nil {
+			return nil, err
+		}
+	case kind >= 32: // LPC
+		order := kind - 31
+		if order > blockSize {
+			return nil, errors.New("predictor order above block size")
+		}
+		for i := 0; i < order; i++ {
+			samples[i] = r.signed(bits)
+		}
+		precision := int(r.read(4)) + 1
+		if precision == 16 {
+			return nil, errors.New("invalid coefficient precision")
+		}
+		shift := int(r.signed(5))
+		if shift < 0 {
+			return nil, errors.New("negative prediction shift")
+		}
+		coefficients := make([]int64, order)
+		for i := range coefficients {
+			coefficients[i] = r.signed(precision)
+		}
+		if err := decodeFLACPrediction(r, samples, coefficients, shift); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("reserved subframe type %d", kind)
+	}
+
+	for i := range samples {
+		samples[i] <<= wasted
+	}
+	return samples, r.err
+}
+
+// fixedCoefficients are the predictors of FIXED subframes by order, most
+// recent sample first
+var fixedCoefficients = [][]int64{{}, {1}, {2, -1}, {3, -3, 1}, {4, -6, 4, -1}}
+
+// decodeFLACPrediction reads the residual and restores the samples after
+// the warm-up ones, predicting each from those before it
+func decodeFLACPrediction(r *bitReader, samples []int64, coefficients []int64, shift int) error {
+	order := len(coefficients)
+	if err := decodeFLACResidual(r, samples, order); err != nil {
+		return err
+	}
+	for i := order; i < len(samples); i++ {
+		var prediction int64
+		for j, c := range coefficients {
+			prediction += c * samples[i-1-j]
+		}
+		samples[i] += prediction >> shift
+	}
+	return nil
+}
+
+// decodeFLACResidual reads the Rice-coded residual into samples after the
+// warm-up samples
+func decodeFLACResidual(r *bitReader, samples []int64, order int) error {
+	method := r.read(2)
+	if method > 1 {
+		return fmt.Errorf("reserved residual coding method %d", method)
+	}
+	paramBits := 4 + int(method)
+	escape := uint64(1)<<paramBits - 1
+
+	// Each partition holds len(samples)>>partitionOrder samples, the first
+	// one less the warm-up. Some encoders pick an order that doesn't divide
+	// a short last block; the samples past the last partition are left
+	// without a residual.
+	partitionOrder := r.read(4)
+	partitions := 1 << partitionOrder
+	size := len(samples) >> partitionOrder
+	if size < order {
+		return fmt.Errorf("invalid residual partition order %d", partitionOrder)
+	}
+	i := order
+	for p := 0; p < partitions; p++ {
+		end := (p + 1) * size
+		param := r.read(paramBits)
+		if param == escape {
+			bits := int(r.read(5))
+			for ; i < end; i++ {
+				samples[i] = r.signed(bits)
+			}
+			continue
+		}
+		for ; i < end; i++ {
+			v := uint64(r.unary())<<param | r.read(int(param))
+			// Zigzag coded: 0, -1, 1, -2, 2...
+			samples[i] = int64(v>>1) ^ -int64(v&1)
+		}
+		if r.err != nil {
+			return r.err
+		}
+	}
+	return nil
+}
+
+// flacCRC16 returns the CRC-16 of a frame, polynomial 0x8005 with no
+// reflection
+func flacCRC16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// bitReader reads big-endian bit fields. Reading past the end returns
+// zeros and sets err.
+type bitReader struct {
+	data []byte
+	pos  int // in bits
+	err  error
+}
+
+// read returns the next n bits, up to 64
+func (r *bitReader) read(n int) uint64 {
+	var v uint64
+	for n > 0 {
+		if r.pos/8 >= len(r.data) {
+			r.err = errors.New("unexpected end of data")
+			return 0
+		}
+		// Take as many bits as are left in the current byte
+		avail := 8 - r.pos%8
+		take := min(n, avail)
+		bits := uint64(r.data[r.pos/8]>>(avail-take)) & (1<<take - 1)
+		v = v<<take | bits
+		r.pos += take
+		n -= take
+	}
+	return v
+}
+
+// signed returns the next n bits as a two's complement number
+func (r *bitReader) signed(n int) int64 {
+	if n == 0 {
+		return 0
+	}
+	v := r.read(n)
+	return int64(v<<(64-n)) >> (64 - n)
+}
+
+// unary counts zero bits up to the next one bit
+func (r *bitReader) unary() int {
+	n := 0
+	for r.read(1) == 0 {
+		if r.err != nil {
+			return 0
+		}
+		n++
+	}
+	return n
+}
+
+// align skips to the next byte boundary
+func (r *bitReader) align() {
+	r.pos = (r.pos + 7) / 8 * 8
+}
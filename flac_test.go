@@ -0,0 +1,371 @@
+package main
+
+import (
+	"math/bits"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// bitWriter writes big-endian bit fields, the inverse of bitReader
+type bitWriter struct {
+	buf []byte
+	n   int // bits written
+}
+
+func (w *bitWriter) write(v int64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		w.buf[len(w.buf)-1] |= byte(v>>i&1) << (7 - w.n%8)
+		w.n++
+	}
+}
+
+func (w *bitWriter) unary(n int) {
+	for ; n > 0; n-- {
+		w.write(0, 1)
+	}
+	w.write(1, 1)
+}
+
+func (w *bitWriter) align() {
+	w.n = (w.n + 7) / 8 * 8
+}
+
+// flacSubframe writes a subframe of samples with bits per sample
+type flacSubframe func(w *bitWriter, samples []int64, bits int)
+
+// flacResidual writes a Rice-coded residual in one partition
+func flacResidual(w *bitWriter, residual []int64, param int) {
+	flacPartitions(w, residual, 0, 0, param)
+}
+
+// flacPartitions writes a residual after order warm-up samples in
+// len(params) partitions, with 4-bit Rice parameters for method 0 and
+// 5-bit ones for method 1. The escape parameter writes the partition as
+// 16-bit numbers.
+func flacPartitions(w *bitWriter, residual []int64, order, method int, params ...int) {
+	paramBits := 4 + method
+	escape := 1<<paramBits - 1
+	partitionOrder := bits.TrailingZeros(uint(len(params)))
+	size := (len(residual) + order) >> partitionOrder
+	w.write(int64(method), 2)
+	w.write(int64(partitionOrder), 4)
+	start := 0
+	for p, param := range params {
+		end := (p+1)*size - order
+		w.write(int64(param), paramBits)
+		if param == escape {
+			w.write(16, 5)
+			for _, r := range residual[start:end] {
+				w.write(r, 16)
+			}
+		} else {
+			for _, r := range residual[start:end] {
+				v := r << 1
+				if r < 0 {
+					v = -r<<1 - 1
+				}
+				w.unary(int(v >> param))
+				w.write(v, param)
+			}
+		}
+		start = end
+	}
+}
+
+// predicted returns the residual of samples after the warm-up ones
+func predicted(samples, coefficients []int64, shift int) []int64 {
+	var residual []int64
+	for i := len(coefficients); i < len(samples); i++ {
+		var prediction int64
+		for j, c := range coefficients {
+			prediction += c * samples[i-1-j]
+		}
+		residual = append(residual, samples[i]-prediction>>shift)
+	}
+	return residual
+}
+
+func constantSubframe(w *bitWriter, samples []int64, bits int) {
+	w.write(0, 8)
+	w.write(samples[0], bits)
+}
+
+func verbatimSubframe(w *bitWriter, samples []int64, bits int) {
+	w.write(1<<1, 8)
+	for _, s := range samples {
+		w.write(s, bits)
+	}
+}
+
+func fixedSubframe(order int) flacSubframe {
+	return func(w *bitWriter, samples []int64, bits int) {
+		w.write(int64(8+order)<<1, 8)
+		for _, s := range samples[:order] {
+			w.write(s, bits)
+		}
+		flacResidual(w, predicted(samples, fixedCoefficients[order], 0), 2)
+	}
+}
+
+// partitionedSubframe writes a fixed order 1 subframe with the residual in
+// len(params) partitions, see flacPartitions
+func partitionedSubframe(method int, params ...int) flacSubframe {
+	return func(w *bitWriter, samples []int64, bits int) {
+		w.write(9<<1, 8)
+		w.write(samples[0], bits)
+		flacPartitions(w, predicted(samples, fixedCoefficients[1], 0), 1, method, params...)
+	}
+}
+
+func lpcSubframe(coefficients []int64, shift int) flacSubframe {
+	return func(w *bitWriter, samples []int64, bits int) {
+		order := len(coefficients)
+		w.write(int64(31+order)<<1, 8)
+		for _, s := range samples[:order] {
+			w.write(s, bits)
+		}
+		w.write(7, 4) // 8-bit precision
+		w.write(int64(shift), 5)
+		for _, c := range coefficients {
+			w.write(c, 8)
+		}
+		flacResidual(w, predicted(samples, coefficients, shift), 3)
+	}
+}
+
+// wastedSubframe writes samples that are all multiples of 1<<wasted
+func wastedSubframe(wasted int) flacSubframe {
+	return func(w *bitWriter, samples []int64, bits int) {
+		w.write(1<<1|1, 8)
+		w.unary(wasted - 1)
+		for _, s := range samples {
+			w.write(s>>wasted, bits-wasted)
+		}
+	}
+}
+
+// flacHeader starts a FLAC file with a 42-byte header for 16-bit samples at
+// 8 kHz
+func flacHeader(channels int) *bitWriter {
+	w := &bitWriter{}
+	for _, b := range []byte("fLaC") {
+		w.write(int64(b), 8)
+	}
+	w.write(1, 1) // last metadata block
+	w.write(0, 7) // STREAMINFO
+	w.write(34, 24)
+	w.write(0, 80) // block and frame sizes
+	w.write(8000, 20)
+	w.write(int64(channels-1), 3)
+	w.write(15, 5)
+	w.write(0, 36+128) // total samples, MD5
+	return w
+}
+
+// flacConstantFile builds a FLAC file of two mono frames of constant
+// samples with the given block size and sample rate codes. blockSize and
+// rate are written after the frame number for the codes that need them.
+func flacConstantFile(blockCode, blockSize, rateCode, rate int) []byte {
+	w := flacHeader(1)
+	for frame := range 2 {
+		start := len(w.buf)
+		w.write(0x3FFE, 14)
+		w.write(0, 2)
+		w.write(int64(blockCode), 4)
+		w.write(int64(rateCode), 4)
+		w.write(0, 4) // mono
+		w.write(4, 3) // 16-bit samples
+		w.write(0, 1)
+		w.write(int64(frame), 8)
+		switch blockCode {
+		case 6:
+			w.write(int64(blockSize-1), 8)
+		case 7:
+			w.write(int64(blockSize-1), 16)
+		}
+		switch rateCode {
+		case 12:
+			w.write(int64(rate/1000), 8)
+		case 13:
+			w.write(int64(rate), 16)
+		case 14:
+			w.write(int64(rate/10), 16)
+		}
+		w.write(0, 8) // CRC-8, not checked
+		constantSubframe(w, []int64{int64(frame + 1)}, 16)
+		w.align()
+		w.write(int64(flacCRC16(w.buf[start:])), 16)
+	}
+	return w.buf
+}
+
+// flacFile builds a FLAC file of one frame with 16-bit samples at 8 kHz.
+// channels holds the samples as coded, e.g. the side channel for
+// assignment flacLeftSide.
+func flacFile(assignment int, subframe flacSubframe, channels ...[]int64) []byte {
+	w := flacHeader(len(channels))
+	w.write(0x3FFE, 14)
+	w.write(0, 2)
+	w.write(7, 4) // 16-bit block size after the header
+	w.write(0, 4) // sample rate from STREAMINFO
+	w.write(int64(assignment), 4)
+	w.write(4, 3) // 16-bit samples
+	w.write(0, 1)
+	w.write(0, 8) // frame number
+	w.write(int64(len(channels[0])-1), 16)
+	w.write(0, 8) // CRC-8, not checked
+	for c, samples := range channels {
+		bits := 16
+		if assignment == flacLeftSide && c == 1 || assignment == flacSideRight && c == 0 || assignment == flacMidSide && c == 1 {
+			bits++
+		}
+		subframe(w, samples, bits)
+	}
+	w.align()
+	w.write(int64(flacCRC16(w.buf[42:])), 16) // the frame starts after the 42-byte header
+	return w.buf
+}
+
+// TestFLACDecoder tests decoding the FLAC subframe types and stereo
+// decorrelation
+func TestFLACDecoder(t *testing.T) {
+	ramp := []int64{-300, -100, 100, 300, 500, 700, 900, 1100}
+	wave := []int64{0, 1000, 1800, 2000, 1500, 600, -500, -1400}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected [][]int64 // per channel
+	}{
+		{"constant", flacFile(0, constantSubframe, []int64{-7, -7, -7}), [][]int64{{-7, -7, -7}}},
+		{"verbatim", flacFile(0, verbatimSubframe, wave), [][]int64{wave}},
+		{"fixed order 0", flacFile(0, fixedSubframe(0), []int64{3, -2, 1}), [][]int64{{3, -2, 1}}},
+		{"fixed order 2", flacFile(0, fixedSubframe(2), ramp), [][]int64{ramp}},
+		{"fixed order 4", flacFile(0, fixedSubframe(4), wave), [][]int64{wave}},
+		{"lpc", flacFile(0, lpcSubframe([]int64{58, -30}, 5), wave), [][]int64{wave}},
+		{"wasted bits", flacFile(0, wastedSubframe(2), []int64{-8, 4, 12}), [][]int64{{-8, 4, 12}}},
+		{"independent stereo", flacFile(1, verbatimSubframe, ramp, wave), [][]int64{ramp, wave}},
+		{"left/side", flacFile(flacLeftSide, verbatimSubframe, ramp, []int64{-300, -1100, -1700, -1700, -1000, 100, 1400, 2500}),
+			[][]int64{ramp, wave}},
+		{"side/right", flacFile(flacSideRight, verbatimSubframe, []int64{-300, -1100, -1700, -1700, -1000, 100, 1400, 2500}, wave),
+			[][]int64{ramp, wave}},
+		// mid = (left + right) >> 1, side = left - right
+		{"mid/side", flacFile(flacMidSide, fixedSubframe(1), []int64{-150, 450, 950, 1150, 1000, 650, 200, -150}, []int64{-300, -1100, -1700, -1700, -1000, 100, 1400, 2500}),
+			[][]int64{ramp, wave}},
+		{"partitions", flacFile(0, partitionedSubframe(0, 9, 10, 11, 8), wave), [][]int64{wave}},
+		{"5-bit Rice parameters", flacFile(0, partitionedSubframe(1, 17, 20), wave), [][]int64{wave}},
+		{"escape code", flacFile(0, partitionedSubframe(0, 15, 9), wave), [][]int64{wave}},
+		{"5-bit escape code", flacFile(0, partitionedSubframe(1, 9, 31), wave), [][]int64{wave}},
+		// Two partitions of 3 samples leave the last one without a residual,
+		// so it is predicted from the one before
+		{"partitions short of the block", flacFile(0, partitionedSubframe(0, 10, 10), wave[:7]),
+			[][]int64{{0, 1000, 1800, 2000, 1500, 600, 600}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sound, err := DecodeSound(tt.data)
+			if err != nil {
+				t.Fatalf("DecodeSound() error = %v", err)
+			}
+			if sound.SampleRate != 8000 || sound.Channels != len(tt.expected) {
+				t.Fatalf("DecodeSound() format = %d Hz, %d channels, want 8000 Hz, %d channels", sound.SampleRate, sound.Channels, len(tt.expected))
+			}
+			for c, samples := range tt.expected {
+				var expected, got []float32
+				for i, v := range samples {
+					expected = append(expected, intSample(v, 16))
+					got = append(got, sound.Samples[i*sound.Channels+c])
+				}
+				if !slices.Equal(got, expected) {
+					t.Errorf("channel %d = %v, want %v", c, got, expected)
+				}
+			}
+		})
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		data := flacFile(0, verbatimSubframe, wave)
+		_, err := DecodeSound(data[:len(data)-8])
+		if err == nil || !strings.HasPrefix(err.Error(), "FLAC: frame at byte") {
+			t.Errorf("DecodeSound() error = %v, want a frame error", err)
+		}
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		data := flacFile(0, verbatimSubframe, wave)
+		data[len(data)-4] ^= 1
+		_, err := DecodeSound(data)
+		if err == nil || !strings.HasSuffix(err.Error(), "CRC-16 mismatch") {
+			t.Errorf("DecodeSound() error = %v, want a CRC error", err)
+		}
+	})
+}
+
+// TestFLACFrameHeader tests the block size and sample rate codes of the
+// frame header
+func TestFLACFrameHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		blockCode int
+		blockSize int
+		rateCode  int
+		rate      int
+	}{
+		{"192 samples", 1, 192, 0, 0},
+		{"576 << n samples", 4, 2304, 0, 0},
+		{"8-bit block size", 6, 100, 0, 0},
+		{"16-bit block size", 7, 1000, 0, 0},
+		{"256 << n samples", 12, 4096, 0, 0},
+		{"coded sample rate", 1, 192, 10, 48000},
+		{"sample rate in kHz", 1, 192, 12, 8000},
+		{"sample rate in Hz", 1, 192, 13, 11025},
+		{"sample rate in tens of Hz", 1, 192, 14, 22050},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sound, err := DecodeSound(flacConstantFile(tt.blockCode, tt.blockSize, tt.rateCode, tt.rate))
+			if err != nil {
+				t.Fatalf("DecodeSound() error = %v", err)
+			}
+			if sound.Frames() != 2*tt.blockSize {
+				t.Fatalf("Frames() = %d, want %d", sound.Frames(), 2*tt.blockSize)
+			}
+			// Each frame holds its number plus one
+			if sound.Samples[0] != intSample(1, 16) || sound.Samples[tt.blockSize] != intSample(2, 16) {
+				t.Errorf("frames start with %v and %v, want %v and %v",
+					sound.Samples[0], sound.Samples[tt.blockSize], intSample(1, 16), intSample(2, 16))
+			}
+		})
+	}
+}
+
+// TestFLACFile tests decoding a file from a real encoder: 38 frames of
+// 576 samples, the last one short, with partitioned residuals. Every frame
+// must match its CRC-16.
+func TestFLACFile(t *testing.T) {
+	data, err := os.ReadFile("testdata/test.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sound, err := DecodeSound(data)
+	if err != nil {
+		t.Fatalf("DecodeSound() error = %v", err)
+	}
+	if sound.SampleRate != 8000 || sound.Channels != 1 || sound.Frames() != 21751 {
+		t.Fatalf("DecodeSound() = %d Hz, %d channels, %d frames, want 8000 Hz, 1 channel, 21751 frames",
+			sound.SampleRate, sound.Channels, sound.Frames())
+	}
+	samples := map[int]int64{0: 0, 575: -9, 576: 16, 1000: -273, 5000: -4168, 10000: 15723, 21312: 17}
+	for i, v := range samples {
+		if sound.Samples[i] != intSample(v, 16) {
+			t.Errorf("sample %d = %v, want %v", i, sound.Samples[i], intSample(v, 16))
+		}
+	}
+}
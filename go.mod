@@ -4,10 +4,13 @@ go 1.24.0
 
 require (
 	github.com/ebitengine/oto/v3 v3.4.0
+	github.com/godbus/dbus/v5 v5.1.0
 	github.com/hajimehoshi/go-mp3 v0.3.4
+	github.com/jfreymuth/oggvorbis v1.0.5
 )
 
 require (
 	github.com/ebitengine/purego v0.9.0 // indirect
+	github.com/jfreymuth/vorbis v1.0.2 // indirect
 	golang.org/x/sys v0.36.0 // indirect
 )
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultHookTimeout is how long a hook command may run before it is killed
+const defaultHookTimeout = 10 * time.Second
+
+// Hooks runs user commands on timer events. Commands run asynchronously in
+// their own process group, so a slow hook never delays the timer and a
+// timed out hook is killed together with its children.
+type Hooks struct {
+	Commands map[EventType]string
+	Timeout  time.Duration
+	// OnError is called when a hook fails or times out
+	OnError func(eventType EventType, err error)
+
+	wg sync.WaitGroup
+}
+
+// NewHooks creates hooks for the given commands. Empty commands are ignored.
+func NewHooks(commands map[EventType]string, timeout time.Duration) *Hooks {
+	h := &Hooks{
+		Commands: make(map[EventType]string),
+		Timeout:  timeout,
+		OnError: func(eventType EventType, err error) {
+			fmt.Fprintf(os.Stderr, "Error running %s hook: %v\n", eventType, err)
+		},
+	}
+	for eventType, command := range commands {
+		if command != "" {
+			h.Commands[eventType] = command
+		}
+	}
+	return h
+}
+
+// Run starts the hook for the event, if one is configured
+func (h *Hooks) Run(event Event) {
+	command, ok := h.Commands[event.Type]
+	if !ok {
+		return
+	}
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		if err := h.run(command, event); err != nil {
+			h.OnError(event.Type, err)
+		}
+	}()
+}
+
+// Wait blocks until all running hooks have finished
+func (h *Hooks) Wait() {
+	h.wg.Wait()
+}
+
+func (h *Hooks) run(command string, event Event) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(), hookEnv(event)...)
+	cmd.Stderr = &stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", h.Timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// hookEnv returns the environment variables describing the event
+func hookEnv(event Event) []string {
+	return []string{
+		"BLEEP_EVENT=" + string(event.Type),
+		"BLEEP_TIMESTAMP=" + event.Timestamp.Format(time.RFC3339),
+		"BLEEP_BEEP_TYPE=" + event.BeepType,
+		"BLEEP_LABEL=" + event.Label,
+		"BLEEP_SEGMENT=" + strconv.Itoa(event.Segment),
+		"BLEEP_SEGMENTS=" + strconv.Itoa(event.Segments),
+		"BLEEP_REMAINING=" + strconv.Itoa(event.Remaining),
+		"BLEEP_COUNT=" + strconv.Itoa(event.BeepCount),
+		"BLEEP_PAUSED=" + strconv.FormatBool(event.Paused),
+		"BLEEP_PID=" + strconv.Itoa(os.Getpid()),
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestHooksRunWithEnvironment tests that hooks receive event details
+func TestHooksRunWithEnvironment(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+	hooks := NewHooks(map[EventType]string{
+		EventBeep: `echo "$BLEEP_EVENT $BLEEP_LABEL $BLEEP_COUNT $BLEEP_BEEP_TYPE $BLEEP_SEGMENT/$BLEEP_SEGMENTS" > ` + out,
+	}, 5*time.Second)
+	hooks.OnError = func(eventType EventType, err error) {
+		t.Errorf("unexpected hook error: %v", err)
+	}
+
+	hooks.Run(Event{Type: EventBeep, BeepType: "manual", Label: "work", BeepCount: 3, Segment: 1, Segments: 2})
+	hooks.Wait()
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "beep work 3 manual 1/2" {
+		t.Errorf("hook saw %q, want %q", got, "beep work 3 manual 1/2")
+	}
+}
+
+// TestHooksIgnoreUnconfiguredEvents tests that only configured events run hooks
+func TestHooksIgnoreUnconfiguredEvents(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "ran")
+	hooks := NewHooks(map[EventType]string{
+		EventBeep:  "touch " + out,
+		EventPause: "",
+	}, 5*time.Second)
+
+	if _, ok := hooks.Commands[EventPause]; ok {
+		t.Error("empty commands should not be registered")
+	}
+
+	hooks.Run(Event{Type: EventTick})
+	hooks.Run(Event{Type: EventPause})
+	hooks.Wait()
+
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("hook ran for an event it was not configured for")
+	}
+}
+
+// TestHooksReportFailures tests that failing and slow hooks are reported
+func TestHooksReportFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		timeout  time.Duration
+		contains string
+	}{
+		{name: "non-zero exit", command: "echo broken >&2; exit 3", timeout: 5 * time.Second, contains: "broken"},
+		{name: "timeout", command: "sleep 10", timeout: 100 * time.Millisecond, contains: "timed out"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var reported []error
+			hooks := NewHooks(map[EventType]string{EventBeep: tt.command}, tt.timeout)
+			hooks.OnError = func(eventType EventType, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				reported = append(reported, err)
+			}
+
+			start := time.Now()
+			hooks.Run(Event{Type: EventBeep})
+			hooks.Wait()
+
+			if len(reported) != 1 {
+				t.Fatalf("expected 1 reported error, got %d", len(reported))
+			}
+			if !strings.Contains(reported[0].Error(), tt.contains) {
+				t.Errorf("error %q does not contain %q", reported[0], tt.contains)
+			}
+			if time.Since(start) > 5*time.Second {
+				t.Error("hook was not killed in time")
+			}
+		})
+	}
+}
+
+// TestHooksRunAsynchronously tests that Run does not wait for the command
+func TestHooksRunAsynchronously(t *testing.T) {
+	hooks := NewHooks(map[EventType]string{EventBeep: "sleep 1"}, 5*time.Second)
+
+	start := time.Now()
+	hooks.Run(Event{Type: EventBeep})
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("Run blocked on the hook command")
+	}
+	hooks.Wait()
+}
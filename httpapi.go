@@ -0,0 +1,376 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed status.html
+var statusPage []byte
+
+// Control commands accepted by the timer loop
+const (
+	CommandPause  = "pause"
+	CommandResume = "resume"
+	CommandSkip   = "skip"
+	CommandReset  = "reset"
+	CommandBeep   = "beep"
+	CommandTime   = "time"
+	CommandLap    = "lap"
+	CommandAck    = "ack"
+)
+
+const (
+	// commandTimeout is how long an HTTP request waits for the timer loop
+	commandTimeout = 5 * time.Second
+	// subscriberBuffer is the number of events buffered per event stream;
+	// slow clients miss events rather than blocking the timer
+	subscriberBuffer = 16
+	// maxRemaining is the longest time left POST /time accepts
+	maxRemaining = 24 * time.Hour
+	// headerTimeout is how long a client may take to send request headers
+	headerTimeout = 10 * time.Second
+)
+
+// errAlreadyPaused and friends are returned for commands that do not apply
+// to the current state; the API reports them as 409 Conflict
+var (
+	errAlreadyPaused  = errors.New("timer is already paused")
+	errAlreadyRunning = errors.New("timer is already running")
+	errPaused         = errors.New("timer is paused")
+	errStopwatch      = errors.New("not available in stopwatch mode")
+	errNotStopwatch   = errors.New("laps are only recorded in stopwatch mode")
+	errNoAlarm        = errors.New("no alarm is sounding")
+)
+
+// Command is a control request sent to the timer loop
+type Command struct {
+	Name      string
+	Remaining time.Duration // new remaining time for CommandTime
+	result    chan error
+}
+
+// Reply reports the outcome of the command to the sender
+func (c Command) Reply(err error) {
+	if c.result != nil {
+		c.result <- err
+	}
+}
+
+// Status is the timer state returned by GET /status
+type Status struct {
+	Version   int       `json:"version"`
+	State     string    `json:"state"`
+	Segment   int       `json:"segment"`
+	Segments  int       `json:"segments"`
+	Label     string    `json:"label"`
+	Remaining int       `json:"remaining"`
+	BeepCount int       `json:"beep_count"`
+	Paused    bool      `json:"paused"`
+	Updated   time.Time `json:"updated"`
+	Stopwatch bool      `json:"stopwatch,omitempty"`
+	Elapsed   int       `json:"elapsed,omitempty"`
+	Lap       int       `json:"lap,omitempty"`
+}
+
+// statusFromEvent derives the timer status from the latest event
+func statusFromEvent(event Event) Status {
+	state := "counting"
+	switch {
+	case event.Type == EventFinish:
+		state = "finished"
+	case event.Alarm:
+		state = "alarm"
+	case event.Paused:
+		state = "paused"
+	}
+	return Status{
+		Version:   EventSchemaVersion,
+		State:     state,
+		Segment:   event.Segment,
+		Segments:  event.Segments,
+		Label:     event.Label,
+		Remaining: event.Remaining,
+		BeepCount: event.BeepCount,
+		Paused:    event.Paused,
+		Updated:   event.Timestamp,
+		Stopwatch: event.Stopwatch,
+		Elapsed:   event.Elapsed,
+		Lap:       event.Lap,
+	}
+}
+
+// APIServer serves the local HTTP API: status, control commands, a
+// Server-Sent Events stream of timer events, metrics and a small status page.
+type APIServer struct {
+	// Metrics is served on /metrics when set
+	Metrics *Metrics
+	// Addr is the address the API listens on. Requests must name it or a
+	// loopback host, see allowedHost.
+	Addr string
+
+	commands chan Command
+
+	mu          sync.Mutex
+	latest      Event
+	subscribers map[chan Event]struct{}
+}
+
+// NewAPIServer creates an API server. Commands must be read from Commands.
+func NewAPIServer() *APIServer {
+	return &APIServer{
+		commands:    make(chan Command),
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Commands returns the control commands received over HTTP
+func (s *APIServer) Commands() <-chan Command {
+	return s.commands
+}
+
+// Publish records the event as the latest state and sends it to all event
+// stream subscribers
+func (s *APIServer) Publish(event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// A beep or skip describes the interval that ended, the segment-start
+	// that follows it carries the new state; a lap describes the recorded lap
+	if event.Type != EventBeep && event.Type != EventSkip && event.Type != EventLap {
+		s.latest = event
+	}
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Server returns an HTTP server for the API
+func (s *APIServer) Server() *http.Server {
+	return &http.Server{Handler: s.Handler(), ReadHeaderTimeout: headerTimeout}
+}
+
+// Handler returns the HTTP handler for the API
+func (s *APIServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handlePage)
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	if s.Metrics != nil {
+		mux.Handle("GET /metrics", s.Metrics)
+	}
+	for _, name := range []string{CommandPause, CommandResume, CommandSkip, CommandReset, CommandBeep, CommandTime, CommandLap, CommandAck} {
+		mux.HandleFunc("POST /"+name, s.handleCommand(name))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowedHost(r.Host, s.Addr) {
+			writeError(w, http.StatusMisdirectedRequest, fmt.Errorf("unknown host %q", r.Host))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (s *APIServer) status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return statusFromEvent(s.latest)
+}
+
+func (s *APIServer) handlePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(statusPage)
+}
+
+func (s *APIServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (s *APIServer) handleCommand(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !sameOrigin(r) {
+			writeError(w, http.StatusForbidden, errors.New("cross-origin request"))
+			return
+		}
+
+		cmd := Command{Name: name, result: make(chan error, 1)}
+		if name == CommandTime {
+			remaining, err := parseRemaining(r)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			cmd.Remaining = remaining
+		}
+
+		timeout := time.After(commandTimeout)
+		select {
+		case s.commands <- cmd:
+		case <-timeout:
+			writeError(w, http.StatusServiceUnavailable, errors.New("timer is not responding"))
+			return
+		}
+		select {
+		case err := <-cmd.result:
+			if err != nil {
+				writeError(w, http.StatusConflict, err)
+				return
+			}
+		case <-timeout:
+			writeError(w, http.StatusServiceUnavailable, errors.New("timer is not responding"))
+			return
+		}
+		writeJSON(w, http.StatusOK, s.status())
+	}
+}
+
+func (s *APIServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	latest := s.latest
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// Start with the current state so clients can render right away
+	if latest.Type != "" {
+		writeSSE(w, latest)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case event := <-ch:
+			writeSSE(w, event)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeSSE writes one event in the Server-Sent Events format
+func writeSSE(w http.ResponseWriter, event Event) {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, FormatEventOutput(event))
+}
+
+// parseRemaining reads the remaining time for POST /time from the
+// "remaining" form value or a JSON body, as a Go duration ("5m30s") or a
+// number of seconds
+func parseRemaining(r *http.Request) (time.Duration, error) {
+	var value string
+	if r.Header.Get("Content-Type") == "application/json" {
+		var body struct {
+			Remaining json.RawMessage `json:"remaining"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return 0, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		if err := json.Unmarshal(body.Remaining, &value); err != nil {
+			value = string(body.Remaining)
+		}
+	} else {
+		value = r.FormValue("remaining")
+	}
+	if value == "" {
+		return 0, errors.New("missing remaining time")
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		secs, serr := strconv.Atoi(value)
+		if serr != nil {
+			return 0, fmt.Errorf("invalid remaining time: %s", value)
+		}
+		if secs > int(maxRemaining/time.Second) {
+			return 0, fmt.Errorf("remaining time must not exceed %s: %s", maxRemaining, value)
+		}
+		d = time.Duration(secs) * time.Second
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("remaining time must be positive: %s", value)
+	}
+	if d > maxRemaining {
+		return 0, fmt.Errorf("remaining time must not exceed %s: %s", maxRemaining, value)
+	}
+	return d, nil
+}
+
+// sameOrigin rejects requests sent by web pages from other origins, so a
+// random website cannot control the timer through the user's browser
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// allowedHost reports whether a request's Host header names the API, so a
+// website can't reach it by rebinding its own name to 127.0.0.1. Loopback
+// hosts and the host of the listen address are allowed; any IP address is
+// when listening on all addresses.
+func allowedHost(host, addr string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	if ip != nil && ip.IsLoopback() {
+		return true
+	}
+
+	listen, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		listen = addr
+	}
+	listenIP := net.ParseIP(listen)
+	switch {
+	case listen == "" || listenIP != nil && listenIP.IsUnspecified():
+		return ip != nil
+	case listenIP != nil:
+		return listenIP.Equal(ip)
+	}
+	return strings.EqualFold(host, listen)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveCommands answers API commands like the timer loop would, recording
+// them and publishing a pause event for CommandPause
+func serveCommands(api *APIServer, received chan<- Command) {
+	for cmd := range api.Commands() {
+		received <- cmd
+		switch cmd.Name {
+		case CommandPause:
+			api.Publish(Event{Type: EventPause, Segment: 1, Segments: 2, Remaining: 60, Paused: true})
+			cmd.Reply(nil)
+		case CommandResume:
+			cmd.Reply(errAlreadyRunning)
+		default:
+			cmd.Reply(nil)
+		}
+	}
+}
+
+// TestAPIServerCommands tests the control endpoints
+func TestAPIServerCommands(t *testing.T) {
+	api := NewAPIServer()
+	received := make(chan Command, 1)
+	go serveCommands(api, received)
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		path          string
+		body          string
+		contentType   string
+		wantCode      int
+		wantRemaining time.Duration
+	}{
+		{name: "pause", path: "/pause", wantCode: http.StatusOK},
+		{name: "conflict", path: "/resume", wantCode: http.StatusConflict},
+		{name: "skip", path: "/skip", wantCode: http.StatusOK},
+		{name: "time form", path: "/time", body: "remaining=5m30s",
+			contentType: "application/x-www-form-urlencoded", wantCode: http.StatusOK, wantRemaining: 330 * time.Second},
+		{name: "time json seconds", path: "/time", body: `{"remaining": 90}`,
+			contentType: "application/json", wantCode: http.StatusOK, wantRemaining: 90 * time.Second},
+		{name: "time json duration", path: "/time", body: `{"remaining": "2m"}`,
+			contentType: "application/json", wantCode: http.StatusOK, wantRemaining: 2 * time.Minute},
+		{name: "time invalid", path: "/time", body: "remaining=soon",
+			contentType: "application/x-www-form-urlencoded", wantCode: http.StatusBadRequest},
+		{name: "time negative", path: "/time", body: "remaining=-5",
+			contentType: "application/x-www-form-urlencoded", wantCode: http.StatusBadRequest},
+		{name: "time too long", path: "/time", body: "remaining=25h",
+			contentType: "application/x-www-form-urlencoded", wantCode: http.StatusBadRequest},
+		{name: "time seconds overflow", path: "/time", body: `{"remaining": 9223372036854775807}`,
+			contentType: "application/json", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(server.URL+tt.path, tt.contentType, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusBadRequest {
+				return
+			}
+
+			cmd := <-received
+			if cmd.Name != strings.TrimPrefix(tt.path, "/") {
+				t.Errorf("command = %q, want %q", cmd.Name, tt.path)
+			}
+			if cmd.Remaining != tt.wantRemaining {
+				t.Errorf("Remaining = %v, want %v", cmd.Remaining, tt.wantRemaining)
+			}
+		})
+	}
+
+	// The pause published by the loop is reflected in the status
+	resp, err := http.Get(server.URL + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status.State != "paused" || status.Remaining != 60 || status.Segments != 2 {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+// TestAPIServerCrossOrigin tests that commands from other origins and
+// requests for other hosts are rejected
+func TestAPIServerCrossOrigin(t *testing.T) {
+	api := NewAPIServer()
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/pause", nil)
+	req.Header.Set("Origin", "https://example.com")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+
+	// A page on a name rebound to 127.0.0.1 sends its own name as the Host
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/status", nil)
+	req.Host = "rebound.example.com"
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMisdirectedRequest {
+		t.Errorf("rebound status = %d, want %d", resp.StatusCode, http.StatusMisdirectedRequest)
+	}
+
+	// GET is not allowed on command endpoints
+	resp, err = http.Get(server.URL + "/pause")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+// TestAPIServerEvents tests the Server-Sent Events stream
+func TestAPIServerEvents(t *testing.T) {
+	api := NewAPIServer()
+	api.Publish(Event{Type: EventSegmentStart, Segment: 1, Segments: 1, Remaining: 300})
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() (string, Event) {
+		t.Helper()
+		var name string
+		var event Event
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading stream: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+					t.Fatalf("invalid data: %v", err)
+				}
+			case line == "":
+				return name, event
+			}
+		}
+	}
+
+	// The current state comes first
+	if name, event := readEvent(); name != "segment-start" || event.Remaining != 300 {
+		t.Errorf("first event = %q %+v", name, event)
+	}
+
+	// Wait until the stream is subscribed before publishing
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		api.mu.Lock()
+		n := len(api.subscribers)
+		api.mu.Unlock()
+		if n > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	api.Publish(Event{Type: EventTick, Segment: 1, Segments: 1, Remaining: 299})
+	if name, event := readEvent(); name != "tick" || event.Remaining != 299 {
+		t.Errorf("second event = %q %+v", name, event)
+	}
+}
+
+// TestSameOrigin tests the sameOrigin function
+func TestSameOrigin(t *testing.T) {
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://127.0.0.1:7777", true},
+		{"http://localhost:7777", false},
+		{"https://example.com", false},
+	}
+
+	for _, tt := range tests {
+		r := &http.Request{Host: "127.0.0.1:7777", Header: http.Header{}, URL: &url.URL{}}
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := sameOrigin(r); got != tt.want {
+			t.Errorf("sameOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+// TestAllowedHost tests the allowedHost function
+func TestAllowedHost(t *testing.T) {
+	tests := []struct {
+		host string
+		addr string
+		want bool
+	}{
+		{"127.0.0.1:7777", "127.0.0.1:7777", true},
+		{"localhost:7777", "127.0.0.1:7777", true},
+		{"LOCALHOST", "127.0.0.1:7777", true},
+		{"[::1]:7777", "127.0.0.1:7777", true},
+		{"rebound.example.com:7777", "127.0.0.1:7777", false},
+		{"192.168.1.5:7777", "127.0.0.1:7777", false},
+		{"", "127.0.0.1:7777", false},
+		{"192.168.1.5:7777", "192.168.1.5:7777", true},
+		{"192.168.1.6:7777", "192.168.1.5:7777", false},
+		{"192.168.1.5:7777", ":7777", true},
+		{"[fe80::1]:7777", "[::]:7777", true},
+		{"rebound.example.com:7777", "0.0.0.0:7777", false},
+		{"pi.local:7777", "pi.local:7777", true},
+		{"other.local:7777", "pi.local:7777", false},
+	}
+
+	for _, tt := range tests {
+		if got := allowedHost(tt.host, tt.addr); got != tt.want {
+			t.Errorf("allowedHost(%q, %q) = %v, want %v", tt.host, tt.addr, got, tt.want)
+		}
+	}
+
+	if timeout := NewAPIServer().Server().ReadHeaderTimeout; timeout != headerTimeout {
+		t.Errorf("ReadHeaderTimeout = %v, want %v", timeout, headerTimeout)
+	}
+}
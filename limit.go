@@ -0,0 +1,51 @@
+package main
+
+import "time"
+
+// runLimitSlack is how close to the end of a -for run a beep may be and
+// still end it, so a run of whole intervals ends on its last beep rather
+// than just after it
+const runLimitSlack = 250 * time.Millisecond
+
+// RunLimit ends a run after a number of beeps (-count) or a total run time
+// (-for), whichever comes first. Manual beeps restart the interval rather
+// than end it, so they don't count.
+type RunLimit struct {
+	Count    int       // beeps to stop after, 0 for no limit
+	Deadline time.Time // when the run time is up, zero for no limit
+	Beeps    int       // beeps counted so far
+	Reached  bool      // the last beep was played
+}
+
+// NewRunLimit creates a run limit for a run starting now. A zero count or
+// duration means no limit. The run time is wall-clock time, so a suspend
+// counts towards it like it does for -until.
+func NewRunLimit(count int, duration time.Duration, now time.Time) *RunLimit {
+	l := &RunLimit{Count: count}
+	if duration > 0 {
+		l.Deadline = now.Round(0).Add(duration)
+	}
+	return l
+}
+
+// Beep counts a beep and reports whether it is the last of the run
+func (l *RunLimit) Beep(now time.Time) bool {
+	l.Beeps++
+	if l.Count > 0 && l.Beeps >= l.Count || !l.Deadline.IsZero() && !now.Add(runLimitSlack).Before(l.Deadline) {
+		l.Reached = true
+	}
+	return l.Reached
+}
+
+// Next returns the time until the run time is up, false without -for
+func (l *RunLimit) Next(now time.Time) (time.Duration, bool) {
+	if l.Deadline.IsZero() {
+		return 0, false
+	}
+	return max(l.Deadline.Sub(now), 0), true
+}
+
+// Expired reports whether the run time is up
+func (l *RunLimit) Expired(now time.Time) bool {
+	return !l.Deadline.IsZero() && !now.Before(l.Deadline)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRunLimit tests ending a run after -count beeps or -for run time
+func TestRunLimit(t *testing.T) {
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("count", func(t *testing.T) {
+		l := NewRunLimit(3, 0, start)
+		for i := 1; i <= 3; i++ {
+			last := l.Beep(start.Add(time.Duration(i) * time.Minute))
+			if last != (i == 3) || l.Reached != last {
+				t.Errorf("beep %d: Beep() = %v, Reached = %v", i, last, l.Reached)
+			}
+		}
+		if _, ok := l.Next(start); ok {
+			t.Error("Next() ok without -for")
+		}
+		if l.Expired(start.Add(24 * time.Hour)) {
+			t.Error("Expired() without -for")
+		}
+	})
+
+	t.Run("run time", func(t *testing.T) {
+		l := NewRunLimit(0, 30*time.Minute, start)
+		if d, ok := l.Next(start.Add(10 * time.Minute)); !ok || d != 20*time.Minute {
+			t.Errorf("Next() = %v, %v, want 20m, true", d, ok)
+		}
+		if l.Beep(start.Add(10 * time.Minute)) {
+			t.Error("Beep() = true 20 minutes before the end")
+		}
+		// A beep just before the end, as the last of whole intervals, ends
+		// the run
+		if !l.Beep(start.Add(30*time.Minute - 10*time.Millisecond)) {
+			t.Error("Beep() = false at the end")
+		}
+		if l.Expired(start.Add(30*time.Minute - time.Second)) {
+			t.Error("Expired() before the end")
+		}
+		if !l.Expired(start.Add(30 * time.Minute)) {
+			t.Error("Expired() = false at the end")
+		}
+		if d, _ := l.Next(start.Add(time.Hour)); d != 0 {
+			t.Errorf("Next() after the end = %v, want 0", d)
+		}
+	})
+
+	t.Run("whichever comes first", func(t *testing.T) {
+		l := NewRunLimit(5, time.Hour, start)
+		if !l.Beep(start.Add(time.Hour)) {
+			t.Error("Beep() = false at the end of the run time")
+		}
+	})
+}
+
+// TestFormatFinishedOutput tests the output of a timer that finished
+func TestFormatFinishedOutput(t *testing.T) {
+	timestamp := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	data := TemplateData{Index: 1, Count: 1, BeepCount: 3, State: "finished"}
+
+	tests := []struct {
+		name     string
+		mode     OutputMode
+		expected string
+	}{
+		{"json", ModeJSON, `{"text":"Done","tooltip":"Finished after 3 beeps","class":"finished","remaining":0}`},
+		{"watch", ModeWatch, "DONE"},
+		{"tmux", ModeTmux, "#[fg=#89b4fa]Done#[default]"},
+		// The session summary follows in these modes
+		{"verbose", ModeVerbose, ""},
+		{"default", ModeDefault, ""},
+		{"events", ModeEvents, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := OutputConfig{Mode: tt.mode, MinutesList: []int{25}, SecondsList: []int{0}, IntervalCount: 1}
+			if result := FormatOutput(config, data, timestamp); result != tt.expected {
+				t.Errorf("FormatOutput() = %q, want %q", result, tt.expected)
+			}
+			if result := FormatStopwatchOutput(config, data, nil, timestamp); result != tt.expected {
+				t.Errorf("FormatStopwatchOutput() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestStatusFromEventFinished tests the API state of a finished timer
+func TestStatusFromEventFinished(t *testing.T) {
+	if state := statusFromEvent(Event{Type: EventFinish}).State; state != "finished" {
+		t.Errorf("State = %q, want finished", state)
+	}
+}
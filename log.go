@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"log/syslog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// journalSocket is where systemd-journald accepts native protocol datagrams
+const journalSocket = "/run/systemd/journal/socket"
+
+// Log sinks selectable with -log
+const (
+	LogText     = "text"
+	LogJSON     = "json"
+	LogSyslog   = "syslog"
+	LogJournald = "journald"
+)
+
+// eventMessages are the log messages for timer events
+var eventMessages = map[EventType]string{
+	EventStart:        "timer started",
+	EventTick:         "tick",
+	EventBeep:         "beep",
+	EventReset:        "interval reset",
+	EventPause:        "timer paused",
+	EventResume:       "timer resumed",
+	EventSkip:         "interval skipped",
+	EventSegmentStart: "interval started",
+	EventFinish:       "timer finished",
+	EventStop:         "timer stopped",
+	EventLap:          "lap recorded",
+	EventClockJump:    "suspend or clock change detected",
+	EventWarning:      "warning before beep",
+	EventAlarm:        "alarm repeated",
+	EventAcknowledge:  "alarm acknowledged",
+}
+
+// parseLogLevel parses a -log-level value. Without one, the stderr sinks
+// only show warnings and errors so they don't clutter the terminal, while
+// syslog and journald also record the timer events.
+func parseLogLevel(s, sink string) (slog.Level, error) {
+	if s == "" {
+		if sink == LogSyslog || sink == LogJournald {
+			return slog.LevelInfo, nil
+		}
+		return slog.LevelWarn, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q (want debug, info, warn or error)", s)
+	}
+	return level, nil
+}
+
+// newLogger creates the logger for a -log sink. Every record carries the
+// instance name so several timers can be told apart.
+func newLogger(sink string, level slog.Level, instance string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch sink {
+	case "", LogText:
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case LogJSON:
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	case LogSyslog:
+		w, err := syslog.New(syslog.LOG_USER|syslog.LOG_INFO, "bleep")
+		if err != nil {
+			return nil, fmt.Errorf("connecting to syslog: %w", err)
+		}
+		handler = newFieldsHandler(level, syslogWriter(w))
+	case LogJournald:
+		w, err := journalWriter(journalSocket)
+		if err != nil {
+			return nil, fmt.Errorf("connecting to journald: %w", err)
+		}
+		handler = newFieldsHandler(level, w)
+	default:
+		return nil, fmt.Errorf("invalid log sink %q (want text, json, syslog or journald)", sink)
+	}
+	return slog.New(handler).With("instance", instance), nil
+}
+
+// logEvent records a timer event; ticks are only logged at debug level
+func logEvent(logger *slog.Logger, event Event) {
+	level := slog.LevelInfo
+	switch event.Type {
+	case EventTick:
+		level = slog.LevelDebug
+	case EventClockJump:
+		level = slog.LevelWarn
+	}
+	attrs := []any{
+		"event", string(event.Type),
+		"segment", event.Segment,
+		"segments", event.Segments,
+		"remaining", event.Remaining,
+		"beep_count", event.BeepCount,
+		"paused", event.Paused,
+	}
+	if event.Label != "" {
+		attrs = append(attrs, "label", event.Label)
+	}
+	if event.BeepType != "" {
+		attrs = append(attrs, "beep_type", event.BeepType)
+	}
+	if event.Type == EventClockJump {
+		attrs = append(attrs, "jump", event.Jump, "policy", event.Policy, "missed", event.Missed)
+	}
+	if event.Type == EventAlarm || event.Type == EventAcknowledge {
+		attrs = append(attrs, "alarm_beeps", event.AlarmBeeps)
+	}
+	if s := event.Summary; s != nil {
+		attrs = append(attrs, "reason", s.Reason, "exit_code", s.ExitCode, "elapsed", s.Elapsed,
+			"beeps", s.Beeps, "completed", s.Completed, "skipped", s.Skipped)
+	}
+	logger.Log(context.Background(), level, eventMessages[event.Type], attrs...)
+}
+
+// logField is one flattened record attribute
+type logField struct {
+	Key   string
+	Value string
+}
+
+// fieldsWriter delivers a record to a sink that takes a message and fields
+type fieldsWriter func(level slog.Level, msg string, fields []logField) error
+
+// fieldsHandler is a slog.Handler for the syslog and journald sinks, which
+// take a message plus flat key/value fields rather than a formatted line
+type fieldsHandler struct {
+	level slog.Leveler
+	write fieldsWriter
+	attrs []logField
+	group string
+}
+
+func newFieldsHandler(level slog.Leveler, write fieldsWriter) *fieldsHandler {
+	return &fieldsHandler{level: level, write: write}
+}
+
+func (h *fieldsHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *fieldsHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := append([]logField(nil), h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.group, a)
+		return true
+	})
+	return h.write(r.Level, r.Message, fields)
+}
+
+func (h *fieldsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]logField(nil), h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = appendAttr(h2.attrs, h.group, a)
+	}
+	return &h2
+}
+
+func (h *fieldsHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.group = joinKey(h.group, name)
+	return &h2
+}
+
+// appendAttr flattens an attribute, naming group members "group.key"
+func appendAttr(fields []logField, group string, a slog.Attr) []logField {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			fields = appendAttr(fields, joinKey(group, a.Key), ga)
+		}
+		return fields
+	}
+	return append(fields, logField{Key: joinKey(group, a.Key), Value: a.Value.String()})
+}
+
+func joinKey(group, key string) string {
+	if group == "" {
+		return key
+	}
+	if key == "" {
+		return group
+	}
+	return group + "." + key
+}
+
+// syslogWriter sends records to syslog as "message key=value ..." lines
+func syslogWriter(w *syslog.Writer) fieldsWriter {
+	return func(level slog.Level, msg string, fields []logField) error {
+		var b strings.Builder
+		b.WriteString(msg)
+		for _, f := range fields {
+			fmt.Fprintf(&b, " %s=%q", f.Key, f.Value)
+		}
+		line := b.String()
+		switch {
+		case level >= slog.LevelError:
+			return w.Err(line)
+		case level >= slog.LevelWarn:
+			return w.Warning(line)
+		case level >= slog.LevelInfo:
+			return w.Info(line)
+		default:
+			return w.Debug(line)
+		}
+	}
+}
+
+// journalWriter sends records to journald using its native protocol, so
+// the attributes become fields like BLEEP_EVENT and BLEEP_INSTANCE that can
+// be matched with journalctl.
+func journalWriter(path string) (fieldsWriter, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+	return func(level slog.Level, msg string, fields []logField) error {
+		var buf bytes.Buffer
+		writeJournalField(&buf, "MESSAGE", msg)
+		writeJournalField(&buf, "PRIORITY", journalPriority(level))
+		writeJournalField(&buf, "SYSLOG_IDENTIFIER", "bleep")
+		for _, f := range fields {
+			writeJournalField(&buf, journalFieldName(f.Key), f.Value)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		_, err := conn.Write(buf.Bytes())
+		return err
+	}, nil
+}
+
+// writeJournalField encodes one field of a journald datagram. Values with
+// newlines use the binary form with an explicit little-endian length.
+func writeJournalField(w io.Writer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(w, "%s=%s\n", name, value)
+		return
+	}
+	fmt.Fprintf(w, "%s\n", name)
+	binary.Write(w, binary.LittleEndian, uint64(len(value)))
+	fmt.Fprintf(w, "%s\n", value)
+}
+
+// journalFieldName turns an attribute key into a journald field name:
+// upper case letters, digits and underscores, prefixed with BLEEP_
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	return "BLEEP_" + name
+}
+
+// journalPriority maps a log level to a syslog priority
+func journalPriority(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "3"
+	case level >= slog.LevelWarn:
+		return "4"
+	case level >= slog.LevelInfo:
+		return "6"
+	default:
+		return "7"
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseLogLevel tests the parseLogLevel function
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		level   string
+		sink    string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"", LogText, slog.LevelWarn, false},
+		{"", LogJSON, slog.LevelWarn, false},
+		{"", LogJournald, slog.LevelInfo, false},
+		{"", LogSyslog, slog.LevelInfo, false},
+		{"debug", LogText, slog.LevelDebug, false},
+		{"ERROR", LogJournald, slog.LevelError, false},
+		{"loud", LogText, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseLogLevel(tt.level, tt.sink)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLogLevel(%q, %q) error = %v, wantErr %v", tt.level, tt.sink, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseLogLevel(%q, %q) = %v, want %v", tt.level, tt.sink, got, tt.want)
+		}
+	}
+
+	if _, err := newLogger("carrier-pigeon", slog.LevelInfo, "1"); err == nil {
+		t.Error("expected error for unknown sink")
+	}
+}
+
+// TestLogEvent tests that ticks are only logged at debug level and that
+// records carry the event fields
+func TestLogEvent(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	logEvent(logger, Event{Type: EventTick, Segment: 1, Segments: 1})
+	if buf.Len() != 0 {
+		t.Errorf("tick logged at info level: %q", buf.String())
+	}
+
+	logEvent(logger, Event{Type: EventBeep, BeepType: "manual", Segment: 1, Segments: 2, Label: "work", BeepCount: 3})
+	line := buf.String()
+	for _, want := range []string{`msg=beep`, `event=beep`, `label=work`, `beep_type=manual`, `beep_count=3`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("log line %q missing %q", line, want)
+		}
+	}
+
+	buf.Reset()
+	logEvent(logger, Event{Type: EventStop, Segment: 1, Segments: 1, Summary: &Summary{Reason: ReasonInterrupted, ExitCode: 130, Elapsed: 90, Beeps: 2, Completed: 1}})
+	line = buf.String()
+	for _, want := range []string{`msg="timer stopped"`, `reason=interrupted`, `exit_code=130`, `elapsed=90`, `beeps=2`, `completed=1`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("log line %q missing %q", line, want)
+		}
+	}
+}
+
+// parseJournalDatagram decodes a journald native protocol datagram
+func parseJournalDatagram(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		nl := bytes.IndexByte(data, '\n')
+		if nl < 0 {
+			t.Fatalf("unterminated field %q", data)
+		}
+		line := string(data[:nl])
+		data = data[nl+1:]
+		if name, value, ok := strings.Cut(line, "="); ok {
+			fields[name] = value
+			continue
+		}
+		// Binary form: name, newline, 64-bit length, value, newline
+		n := binary.LittleEndian.Uint64(data[:8])
+		fields[line] = string(data[8 : 8+n])
+		data = data[8+n+1:]
+	}
+	return fields
+}
+
+// TestJournalWriter tests records sent with the journald native protocol
+func TestJournalWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	write, err := journalWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(newFieldsHandler(slog.LevelInfo, write)).With("instance", "work-timer")
+
+	receive := func() map[string]string {
+		t.Helper()
+		buf := make([]byte, 4096)
+		server.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := server.Read(buf)
+		if err != nil {
+			t.Fatalf("reading datagram: %v", err)
+		}
+		return parseJournalDatagram(t, buf[:n])
+	}
+
+	logEvent(logger, Event{Type: EventSegmentStart, Segment: 2, Segments: 2, Label: "break", Remaining: 300})
+	fields := receive()
+	want := map[string]string{
+		"MESSAGE":           "interval started",
+		"PRIORITY":          "6",
+		"SYSLOG_IDENTIFIER": "bleep",
+		"BLEEP_EVENT":       "segment-start",
+		"BLEEP_INSTANCE":    "work-timer",
+		"BLEEP_LABEL":       "break",
+		"BLEEP_REMAINING":   "300",
+	}
+	for name, value := range want {
+		if fields[name] != value {
+			t.Errorf("%s = %q, want %q", name, fields[name], value)
+		}
+	}
+
+	// Multi-line values use the binary encoding, groups are flattened
+	logger.WithGroup("hook").Error("hook failed", "err", errors.New("exit status 1\nstderr: oops"))
+	fields = receive()
+	if fields["PRIORITY"] != "3" {
+		t.Errorf("PRIORITY = %q, want 3", fields["PRIORITY"])
+	}
+	if fields["BLEEP_HOOK_ERR"] != "exit status 1\nstderr: oops" {
+		t.Errorf("BLEEP_HOOK_ERR = %q", fields["BLEEP_HOOK_ERR"])
+	}
+
+	// Records below the level are dropped
+	logEvent(logger, Event{Type: EventTick, Segment: 1, Segments: 2})
+	logger.Warn("still here")
+	if fields := receive(); fields["MESSAGE"] != "still here" {
+		t.Errorf("MESSAGE = %q, want the warning after the dropped tick", fields["MESSAGE"])
+	}
+}
+
+// TestJournalFieldName tests the journalFieldName function
+func TestJournalFieldName(t *testing.T) {
+	tests := map[string]string{
+		"event":      "BLEEP_EVENT",
+		"beep_count": "BLEEP_BEEP_COUNT",
+		"hook.err":   "BLEEP_HOOK_ERR",
+		"Remaining2": "BLEEP_REMAINING2",
+	}
+	for key, want := range tests {
+		if got := journalFieldName(key); got != want {
+			t.Errorf("journalFieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -2,20 +2,24 @@ package main
 
 import (
 	"bufio"
-	"bytes"
 	_ "embed"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/fs"
+	"math"
+	"net"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/ebitengine/oto/v3"
-	"github.com/hajimehoshi/go-mp3"
+	"github.com/godbus/dbus/v5"
 )
 
 //go:embed beep.mp3
@@ -30,17 +34,16 @@ var version = "dev"
 // It can be replaced in tests to prevent actual sound playback.
 var beepFunc = playBeepImpl
 
-func initAudio() error {
-	// Decode the MP3 data to get audio format info
-	reader := bytes.NewReader(beepMP3)
-	decodedMP3, err := mp3.NewDecoder(reader)
-	if err != nil {
-		return fmt.Errorf("error decoding MP3: %w", err)
-	}
+// onAudioError is called when a beep cannot be played
+var onAudioError = func(err error) {
+	fmt.Fprintf(os.Stderr, "Error playing beep: %v\n", err)
+}
 
-	// Initialize oto context
+// initAudio opens the audio context at sampleRate in the format all sounds
+// are converted to: 16-bit stereo
+func initAudio(sampleRate int) error {
 	op := &oto.NewContextOptions{
-		SampleRate:   decodedMP3.SampleRate(),
+		SampleRate:   sampleRate,
 		ChannelCount: 2,
 		Format:       oto.FormatSignedInt16LE,
 	}
@@ -52,6 +55,7 @@ func initAudio() error {
 	<-readyChan
 
 	audioContext = ctx
+	audioSampleRate = op.SampleRate
 	return nil
 }
 
@@ -61,27 +65,13 @@ func playBeep() {
 	beepFunc()
 }
 
+// beepWaitTimeout limits how long bleep waits for beeps before exiting, in
+// case the audio device hangs
+const beepWaitTimeout = 3 * time.Second
+
 // playBeepImpl is the actual implementation that plays the beep sound.
 func playBeepImpl() {
-	// Play the beep asynchronously so it doesn't block the timer
-	go func() {
-		// Decode the MP3 data each time (creates a fresh reader)
-		reader := bytes.NewReader(beepMP3)
-		decodedMP3, err := mp3.NewDecoder(reader)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error decoding MP3: %v\n", err)
-			return
-		}
-
-		// Create a player and play the sound
-		player := audioContext.NewPlayer(decodedMP3)
-		player.Play()
-
-		// Wait for the sound to finish
-		for player.IsPlaying() {
-			time.Sleep(10 * time.Millisecond)
-		}
-	}()
+	playBeepVolume(1)
 }
 
 func formatDuration(d time.Duration) string {
@@ -94,6 +84,16 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%ds", s)
 }
 
+// formatLongDuration formats a duration like formatDuration, with hours from
+// one hour on (e.g. "2h 5m 3s")
+func formatLongDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if h := d / time.Hour; h > 0 {
+		return fmt.Sprintf("%dh %dm %ds", h, (d%time.Hour)/time.Minute, (d%time.Minute)/time.Second)
+	}
+	return formatDuration(d)
+}
+
 func parseIntList(s string) ([]int, error) {
 	if s == "" {
 		return []int{0}, nil
@@ -110,12 +110,34 @@ func parseIntList(s string) ([]int, error) {
 	return result, nil
 }
 
+// parseLabels splits a comma-separated list of interval labels
+func parseLabels(s string) []string {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
+
+// labelFor returns the label of the given interval, or an empty string if
+// fewer labels than intervals were given
+func labelFor(labels []string, intervalIndex int) string {
+	if intervalIndex < len(labels) {
+		return labels[intervalIndex]
+	}
+	return ""
+}
+
 // WaybarOutput represents JSON output for Waybar integration
 type WaybarOutput struct {
 	Text      string `json:"text"`
 	Tooltip   string `json:"tooltip"`
 	Class     string `json:"class"`
 	Remaining int    `json:"remaining"`
+	Elapsed   int    `json:"elapsed,omitempty"` // stopwatch mode only
 }
 
 // OutputMode represents the output format mode
@@ -126,8 +148,23 @@ const (
 	ModeVerbose
 	ModeJSON
 	ModeWatch
+	ModePolybar
+	ModeI3blocks
+	ModeTmux
+	ModeEvents
 )
 
+// lineOriented reports whether the mode prints one complete line per update,
+// as opposed to the verbose mode which redraws the current line in place.
+func (m OutputMode) lineOriented() bool {
+	switch m {
+	case ModeJSON, ModeWatch, ModePolybar, ModeI3blocks, ModeTmux, ModeEvents:
+		return true
+	default:
+		return false
+	}
+}
+
 // TimerState represents the current state of the timer
 type TimerState struct {
 	Intervals     []time.Duration
@@ -138,6 +175,9 @@ type TimerState struct {
 	Paused        bool
 	PausedAt      time.Duration
 	NextBeep      time.Time
+	Schedule      Schedule // set for wall-clock timers, replaces the intervals
+	Done          bool     // the schedule has no more beeps
+	Aligned       bool     // the intervals follow the clock, see alignIntervals
 }
 
 // NewTimerState creates a new timer state with the given intervals
@@ -159,6 +199,26 @@ func NewTimerState(intervals []time.Duration, minutesList, secondsList []int, st
 	return ts
 }
 
+// NewScheduledTimerState creates a timer state whose beeps follow the schedule.
+// The single interval is updated to span from one beep to the next.
+func NewScheduledTimerState(schedule Schedule, startPaused bool) *TimerState {
+	now := time.Now()
+	next := schedule.Next(now)
+	interval := next.Sub(now)
+	ts := &TimerState{
+		Intervals:   []time.Duration{interval},
+		MinutesList: []int{int(interval / time.Minute)},
+		SecondsList: []int{int(interval % time.Minute / time.Second)},
+		Paused:      startPaused,
+		NextBeep:    next,
+		Schedule:    schedule,
+	}
+	if startPaused {
+		ts.PausedAt = interval
+	}
+	return ts
+}
+
 // CurrentInterval returns the current interval duration
 func (ts *TimerState) CurrentInterval() time.Duration {
 	return ts.Intervals[ts.IntervalIndex]
@@ -172,9 +232,12 @@ func (ts *TimerState) AdvanceInterval() {
 // TogglePause toggles the pause state and returns the new pause state
 func (ts *TimerState) TogglePause() bool {
 	if ts.Paused {
-		// Resume: set nextBeep based on remaining time
+		// Resume: set nextBeep based on remaining time. Scheduled beeps
+		// stay at their wall-clock time.
 		ts.Paused = false
-		ts.NextBeep = time.Now().Add(ts.PausedAt)
+		if ts.Schedule == nil {
+			ts.NextBeep = time.Now().Add(ts.PausedAt)
+		}
 	} else {
 		// Pause: save remaining time
 		ts.Paused = true
@@ -197,13 +260,101 @@ func (ts *TimerState) Remaining() time.Duration {
 // TriggerBeep increments beep count, advances interval, and resets timer
 func (ts *TimerState) TriggerBeep() {
 	ts.BeepCount++
+	if ts.Schedule != nil {
+		ts.advanceSchedule()
+		return
+	}
+	if ts.Aligned {
+		ts.alignToClock()
+		return
+	}
 	ts.AdvanceInterval()
-	ts.NextBeep = time.Now().Add(ts.CurrentInterval())
+	// Start the next interval where the last one ended, so the time it
+	// takes to handle a beep doesn't add up over many intervals
+	start := time.Now()
+	if late := start.Sub(ts.NextBeep); late >= 0 && late < beepLateTolerance {
+		start = ts.NextBeep
+	}
+	ts.NextBeep = start.Add(ts.CurrentInterval())
+}
+
+// advanceSchedule moves to the scheduled beep after the current one, or
+// marks the timer done if there is none
+func (ts *TimerState) advanceSchedule() {
+	now := time.Now()
+	next := ts.Schedule.Next(maxTime(now, ts.NextBeep))
+	if next.IsZero() {
+		ts.Done = true
+		return
+	}
+	ts.Intervals[ts.IntervalIndex] = next.Sub(now)
+	ts.NextBeep = next
+	if ts.Paused {
+		ts.PausedAt = next.Sub(now)
+	}
+}
+
+// alignToClock moves to the interval the clock is in and ends it on the
+// next boundary
+func (ts *TimerState) alignToClock() {
+	now := time.Now()
+	ts.IntervalIndex, ts.NextBeep = alignIntervals(ts.Intervals, now)
+	if ts.Paused {
+		ts.PausedAt = ts.NextBeep.Sub(now)
+	}
 }
 
-// ResetTimer resets the current interval without advancing
+// ResetTimer resets the current interval without advancing. A scheduled
+// timer goes back to its next scheduled beep, an aligned timer back onto the
+// clock boundaries.
 func (ts *TimerState) ResetTimer() {
-	ts.NextBeep = time.Now().Add(ts.CurrentInterval())
+	if ts.Aligned {
+		ts.alignToClock()
+		return
+	}
+	if ts.Schedule != nil {
+		now := time.Now()
+		if next := ts.Schedule.Next(now); !next.IsZero() {
+			ts.NextBeep = next
+			if ts.Paused {
+				ts.PausedAt = next.Sub(now)
+			}
+		}
+		return
+	}
+	ts.SetRemaining(ts.CurrentInterval())
+}
+
+// maxTime returns the later of two times
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// SetRemaining changes the time left in the current interval
+func (ts *TimerState) SetRemaining(d time.Duration) {
+	if ts.Paused {
+		ts.PausedAt = d
+	} else {
+		ts.NextBeep = time.Now().Add(d)
+	}
+}
+
+// Skip moves to the next interval without beeping. A paused timer stays
+// paused with the full next interval remaining.
+func (ts *TimerState) Skip() {
+	if ts.Schedule != nil {
+		ts.advanceSchedule()
+		return
+	}
+	ts.AdvanceInterval()
+	if ts.Paused {
+		ts.PausedAt = ts.CurrentInterval()
+	} else {
+		ts.NextBeep = time.Now().Add(ts.CurrentInterval())
+	}
 }
 
 // OutputConfig holds configuration for output formatting
@@ -212,6 +363,19 @@ type OutputConfig struct {
 	MinutesList   []int
 	SecondsList   []int
 	IntervalCount int
+	Labels        []string
+	PID           int                // used by polybar action tags to signal this process
+	Template      *template.Template // set by -format, replaces the built-in text
+	Description   string             // describes a wall-clock schedule, replaces the interval in tooltips
+	Hours         bool               // -until: show the time left in hours from one hour on
+}
+
+// duration formats the time left for the output
+func (config OutputConfig) duration(d time.Duration) string {
+	if config.Hours {
+		return formatLongDuration(d)
+	}
+	return formatDuration(d)
 }
 
 // FormatPausedOutput returns the output string for paused state
@@ -228,38 +392,53 @@ func FormatPausedOutput(config OutputConfig, pausedAt time.Duration) string {
 		return string(jsonBytes)
 	case ModeWatch:
 		return "PAUSED"
+	case ModePolybar, ModeI3blocks, ModeTmux:
+		return formatBarOutput(config, "paused", "Paused")
 	case ModeVerbose:
-		return fmt.Sprintf("\rPaused - %s remaining ", formatDuration(pausedAt))
+		return fmt.Sprintf("\rPaused - %s remaining ", config.duration(pausedAt))
 	default:
 		return ""
 	}
 }
 
+// intervalTooltip returns the JSON tooltip describing the current interval
+func intervalTooltip(config OutputConfig, intervalIndex int) string {
+	if config.Description != "" {
+		return config.Description
+	}
+	if config.IntervalCount == 1 {
+		return fmt.Sprintf("%dm %ds", config.MinutesList[0], config.SecondsList[0])
+	}
+	return fmt.Sprintf("Interval %d/%d: %dm %ds", intervalIndex+1, config.IntervalCount,
+		config.MinutesList[intervalIndex], config.SecondsList[intervalIndex])
+}
+
 // FormatTickOutput returns the output string for a timer tick
 func FormatTickOutput(config OutputConfig, remaining time.Duration, intervalIndex int) string {
+	return formatCountdownOutput(config, "counting", remaining, intervalIndex)
+}
+
+// formatCountdownOutput returns the output string for a tick with the given
+// class, "counting", "warning" within the -warn period or "alarm"
+func formatCountdownOutput(config OutputConfig, class string, remaining time.Duration, intervalIndex int) string {
 	remainingSecs := int(remaining.Round(time.Second).Seconds())
 	switch config.Mode {
 	case ModeJSON:
-		var tooltip string
-		if config.IntervalCount == 1 {
-			tooltip = fmt.Sprintf("%dm %ds", config.MinutesList[0], config.SecondsList[0])
-		} else {
-			tooltip = fmt.Sprintf("Interval %d/%d: %dm %ds", intervalIndex+1, config.IntervalCount,
-				config.MinutesList[intervalIndex], config.SecondsList[intervalIndex])
-		}
 		output := WaybarOutput{
-			Text:      formatDuration(remaining),
-			Tooltip:   tooltip,
-			Class:     "counting",
+			Text:      config.duration(remaining),
+			Tooltip:   intervalTooltip(config, intervalIndex),
+			Class:     class,
 			Remaining: remainingSecs,
 		}
 		jsonBytes, _ := json.Marshal(output)
 		return string(jsonBytes)
 	case ModeWatch:
-		return formatDuration(remaining)
+		return config.duration(remaining)
+	case ModePolybar, ModeI3blocks, ModeTmux:
+		return formatBarOutput(config, class, config.duration(remaining))
 	case ModeVerbose:
 		if config.IntervalCount == 1 {
-			return fmt.Sprintf("\rNext beep in: %s ", formatDuration(remaining))
+			return fmt.Sprintf("\rNext beep in: %s ", config.duration(remaining))
 		}
 		return fmt.Sprintf("\rNext beep in: %s (interval %d/%d: %dm %ds) ",
 			formatDuration(remaining), intervalIndex+1, config.IntervalCount,
@@ -283,6 +462,10 @@ func FormatBeepOutput(config OutputConfig, beepCount int, beepType string, inter
 		return string(jsonBytes)
 	case ModeWatch:
 		return "BEEP"
+	case ModePolybar, ModeI3blocks, ModeTmux:
+		return formatBarOutput(config, "beep", "BEEP")
+	case ModeEvents:
+		return ""
 	case ModeVerbose:
 		if config.IntervalCount == 1 {
 			return fmt.Sprintf("\r[%s] Beep #%d (%s)              \n", timestamp.Format("15:04:05"), beepCount, beepType)
@@ -295,6 +478,71 @@ func FormatBeepOutput(config OutputConfig, beepCount int, beepType string, inter
 	}
 }
 
+// FormatWarningOutput returns the output string for a -warn warning
+func FormatWarningOutput(config OutputConfig, remaining time.Duration, intervalIndex int, timestamp time.Time) string {
+	switch config.Mode {
+	case ModeVerbose:
+		return fmt.Sprintf("\r[%s] Warning: %s left              \n", timestamp.Format("15:04:05"), config.duration(remaining))
+	case ModeEvents:
+		return ""
+	case ModeDefault:
+		return fmt.Sprintf("WARNING %s\n", timestamp.Format(time.RFC3339))
+	default:
+		return formatCountdownOutput(config, "warning", remaining, intervalIndex)
+	}
+}
+
+// alarmTooltip returns the JSON tooltip for a repeat of the alarm beep
+func alarmTooltip(alarmBeeps int) string {
+	return fmt.Sprintf("Alarm beep #%d, click to acknowledge", alarmBeeps)
+}
+
+// FormatAlarmOutput returns the output string for a repeat of the -alarm
+// beep
+func FormatAlarmOutput(config OutputConfig, alarmBeeps int, timestamp time.Time) string {
+	switch config.Mode {
+	case ModeJSON:
+		output := WaybarOutput{
+			Text:    "ALARM",
+			Tooltip: alarmTooltip(alarmBeeps),
+			Class:   "alarm",
+		}
+		jsonBytes, _ := json.Marshal(output)
+		return string(jsonBytes)
+	case ModeWatch:
+		return "ALARM"
+	case ModePolybar, ModeI3blocks, ModeTmux:
+		return formatBarOutput(config, "alarm", "ALARM")
+	case ModeEvents:
+		return ""
+	case ModeVerbose:
+		return fmt.Sprintf("\r[%s] Alarm beep #%d, waiting to be acknowledged              \n", timestamp.Format("15:04:05"), alarmBeeps)
+	default:
+		return fmt.Sprintf("ALARM %s\n", timestamp.Format(time.RFC3339))
+	}
+}
+
+// FormatFinishedOutput returns the output string for a timer that finished.
+// The default and verbose modes print the session summary instead.
+func FormatFinishedOutput(config OutputConfig, beepCount int) string {
+	switch config.Mode {
+	case ModeJSON:
+		output := WaybarOutput{
+			Text:    "Done",
+			Tooltip: "Finished after " + plural(beepCount, "beep"),
+			Class:   "finished",
+		}
+		jsonBytes, _ := json.Marshal(output)
+		return string(jsonBytes)
+	case ModeWatch:
+		return "DONE"
+	case ModePolybar, ModeI3blocks, ModeTmux:
+		return formatBarOutput(config, "finished", "Done")
+	default:
+		return ""
+	}
+}
+
 // FormatResetOutput returns the output string for a timer reset
 func FormatResetOutput(config OutputConfig, intervalIndex int, timestamp time.Time) string {
 	if config.Mode != ModeVerbose {
@@ -308,6 +556,35 @@ func FormatResetOutput(config OutputConfig, intervalIndex int, timestamp time.Ti
 		config.MinutesList[intervalIndex], config.SecondsList[intervalIndex])
 }
 
+// FormatOutput returns the output string for the given timer state, using the
+// -format template when one is configured
+func FormatOutput(config OutputConfig, data TemplateData, timestamp time.Time) string {
+	if config.Template != nil {
+		return formatTemplateOutput(config, data, timestamp)
+	}
+	switch data.State {
+	case "paused":
+		if data.Alarm {
+			// A held interval shows its countdown until the alarm is
+			// acknowledged
+			return formatCountdownOutput(config, "alarm", data.Remaining, data.Index-1)
+		}
+		return FormatPausedOutput(config, data.Remaining)
+	case "alarm":
+		return FormatAlarmOutput(config, data.AlarmBeeps, timestamp)
+	case "finished":
+		return FormatFinishedOutput(config, data.BeepCount)
+	case "beep":
+		return FormatBeepOutput(config, data.BeepCount, data.BeepType, data.Index-1, timestamp)
+	case "reset":
+		return FormatResetOutput(config, data.Index-1, timestamp)
+	case "warning":
+		return FormatWarningOutput(config, data.Remaining, data.Index-1, timestamp)
+	default:
+		return formatCountdownOutput(config, data.class(), data.Remaining, data.Index-1)
+	}
+}
+
 // padLists ensures both lists have the same length by padding the shorter one
 // with its last value. Returns the padded lists.
 func padLists(minutesList, secondsList []int) ([]int, []int) {
@@ -351,63 +628,538 @@ func buildIntervals(minutesList, secondsList []int) ([]time.Duration, error) {
 }
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "devices" {
+		os.Exit(runDevices(os.Stdout))
+	}
+
 	minutesStr := flag.String("m", "0", "interval in minutes (comma-separated for multiple intervals)")
 	secondsStr := flag.String("s", "0", "interval in seconds (comma-separated for multiple intervals)")
 	verbose := flag.Bool("v", false, "verbose output (show countdown and status)")
 	interactive := flag.Bool("i", false, "interactive mode (Enter to beep, Backspace to reset)")
+	until := flag.String("until", "", "count down to a wall-clock time instead of an interval (e.g. 17:30 or 2026-10-20T09:00)")
+	daily := flag.Bool("daily", false, "with -until, beep at the same time every day")
+	tz := flag.String("tz", "", "time zone for -until and -align cron expressions (e.g. Europe/Berlin, default: local time)")
+	count := flag.Int("count", 0, "stop after this many beeps, manual ones aside (default: run until stopped)")
+	runFor := flag.Duration("for", 0, "stop after this much time (e.g. 2h, default: run until stopped)")
+	onSuspend := flag.String("on-suspend", string(SuspendFire), "what to do with a beep missed during suspend: fire (play it late), skip (leave it out) or pause (don't count the suspended time)")
+	warnStr := flag.String("warn", "", "warn this long before each beep (comma-separated, e.g. 1m,10s; separate per-interval lists with ';')")
+	flag.StringVar(&warnSound, "warn-sound", WarnSoundTone, "warning sound: tone (a short high tone) or soft (the beep, quieter)")
+	soundFile := flag.String("sound", "", "play this sound file instead of the built-in beep (WAV, OGG Vorbis, FLAC or MP3)")
+	flag.StringVar(&playback.Policy, "overlap", PolicyMix, "what a sound does to sounds still playing: mix (play over them), queue (play after them) or cut (stop them)")
+	flag.IntVar(&playback.MaxSounds, "max-sounds", defaultMaxSounds, "most sounds playing or queued at once; mixing stops the oldest, queueing drops new ones")
+	device := flag.String("device", "", "audio output: an ALSA device or PulseAudio/PipeWire sink listed by 'bleep devices' (default: the system default)")
+	metronomeStr := flag.String("metronome", "", "tick during intervals, accented in the last 5 seconds: a tempo in BPM, on (every second) or off, comma-separated per interval (e.g. on,off)")
+	alarmEvery := flag.Duration("alarm", 0, "repeat the beep this often until acknowledged with Enter, a click, SIGUSR1 or POST /ack (e.g. 5s)")
+	alarmEscalate := flag.Bool("alarm-escalate", false, "with -alarm, start quiet and get louder with every repeat")
+	alarmHold := flag.Bool("alarm-hold", false, "with -alarm, don't start the next interval until the alarm is acknowledged")
+	var align alignFlag
+	announceStr := flag.String("announce", "", "speak the time left this long before each beep (e.g. 5m,1m,3,2,1; separate per-interval lists with ';')")
+	voiceDir := flag.String("voice", "", "directory of voice clips for -announce (default: the clips built into bleep)")
+	flag.Var(&align, "align", "beep on clock boundaries of the interval (e.g. :00, :15, :30, :45 with -m 15), or at the times of a cron expression given as -align='*/20 9-17 * * 1-5'")
+	stopwatchMode := flag.Bool("stopwatch", false, "count up instead of down, -m/-s set an optional beep interval (Enter or SIGUSR2 records a lap)")
 	jsonMode := flag.Bool("json", false, "JSON output for Waybar integration")
 	watchMode := flag.Bool("watch", false, "plain text countdown output")
+	polybarMode := flag.Bool("polybar", false, "output for a polybar custom/script module (tail = true)")
+	i3blocksMode := flag.Bool("i3blocks", false, "output for an i3blocks persistent block (markup=pango)")
+	tmuxMode := flag.Bool("tmux", false, "tmux status output, also written to the status file for -query")
+	eventsMode := flag.Bool("events", false, "NDJSON event stream for scripting (one JSON object per event)")
+	statusFile := flag.String("status-file", defaultStatusFile(), "file holding the latest -tmux output")
+	query := flag.Bool("query", false, "print the latest status of a running -tmux instance and exit")
+	labelsStr := flag.String("labels", "", "interval labels (comma-separated, e.g. work,break)")
+	formatStr := flag.String("format", "", "Go template for the output text (e.g. '{{.Label}} {{clock .Remaining}}')")
+	onBeep := flag.String("on-beep", "", "shell command to run on every beep")
+	onPause := flag.String("on-pause", "", "shell command to run when the timer is paused")
+	onResume := flag.String("on-resume", "", "shell command to run when the timer is resumed")
+	onSegment := flag.String("on-segment", "", "shell command to run when an interval starts")
+	onWarning := flag.String("on-warning", "", "shell command to run on every -warn warning")
+	hookTimeout := flag.Duration("hook-timeout", defaultHookTimeout, "kill hook commands running longer than this")
+	var webhookURLs stringList
+	flag.Var(&webhookURLs, "webhook", "URL to POST beep and interval change events to (repeatable)")
+	webhookTemplate := flag.String("webhook-template", "", "Go template for the webhook request body (default: the JSON event)")
+	notify := flag.Bool("notify", false, "show a desktop notification on beeps and when the timer finishes")
+	notifyTitle := flag.String("notify-title", defaultNotifyTitle, "Go template for the notification title")
+	notifyBody := flag.String("notify-body", defaultNotifyBody, "Go template for the notification body")
+	notifyUrgency := flag.String("notify-urgency", "", "notification urgency per interval: low, normal or critical (comma-separated)")
+	oscNotify := flag.String("osc-notify", "", "terminal notification on beeps: 9 (OSC 9) or 777 (OSC 777)")
+	titleMode := flag.String("title", "", "show the countdown in the terminal title: 0 (OSC 0) or 2 (OSC 2)")
+	bell := flag.Bool("bell", false, "ring the terminal bell on beeps (sets the urgent hint in most terminals)")
+	httpAddr := flag.String("http", "", "serve the HTTP API and status page on this address (e.g. 127.0.0.1:7777)")
+	logSink := flag.String("log", LogText, "log sink: text or json on stderr, syslog or journald")
+	logLevel := flag.String("log-level", "", "minimum log level: debug, info, warn or error (default warn on stderr, info otherwise)")
+	instance := flag.String("instance", "", "instance name attached to log records (default: the PID)")
 	startPaused := flag.Bool("paused", false, "start in paused state (send SIGUSR1 to toggle)")
 	showVersion := flag.Bool("version", false, "show version and exit")
+
+	// install-service needs the timer flags to read the command line it installs
+	if len(os.Args) > 1 && os.Args[1] == "install-service" {
+		os.Exit(runInstallService(os.Args[2:], flag.CommandLine, os.Stdout, os.Stderr))
+	}
 	flag.Parse()
 
 	// Handle version flag
 	if *showVersion {
 		fmt.Println(version)
-		os.Exit(0)
+		os.Exit(exitOK)
+	}
+
+	// One-shot query for tmux status-right
+	if *query {
+		status, err := readStatusFile(*statusFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Println(status)
+		os.Exit(exitOK)
 	}
 
 	// Validate flag combinations
-	if *jsonMode && *watchMode {
-		fmt.Fprintf(os.Stderr, "Error: -json and -watch are mutually exclusive\n")
-		os.Exit(1)
+	mode := ModeDefault
+	selected := 0
+	for _, m := range []struct {
+		set  bool
+		mode OutputMode
+	}{
+		{*jsonMode, ModeJSON},
+		{*watchMode, ModeWatch},
+		{*polybarMode, ModePolybar},
+		{*i3blocksMode, ModeI3blocks},
+		{*tmuxMode, ModeTmux},
+		{*eventsMode, ModeEvents},
+	} {
+		if m.set {
+			mode = m.mode
+			selected++
+		}
+	}
+	if selected > 1 {
+		fmt.Fprintf(os.Stderr, "Error: -json, -watch, -polybar, -i3blocks, -tmux and -events are mutually exclusive\n")
+		os.Exit(exitError)
+	}
+	if mode == ModeDefault && *verbose {
+		mode = ModeVerbose
+	}
+
+	level, err := parseLogLevel(*logLevel, *logSink)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if *instance == "" {
+		*instance = strconv.Itoa(os.Getpid())
+	}
+	logger, err := newLogger(*logSink, level, *instance)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	onAudioError = func(err error) {
+		logger.Error("playing beep failed", "err", err)
 	}
 
 	// Print PID for signal control (useful for Waybar on-click)
-	if *startPaused || *jsonMode || *watchMode {
-		fmt.Fprintf(os.Stderr, "PID: %d (send SIGUSR1 to toggle pause)\n", os.Getpid())
+	if *startPaused || mode.lineOriented() {
+		if *stopwatchMode {
+			fmt.Fprintf(os.Stderr, "PID: %d (send SIGUSR1 to toggle pause, SIGUSR2 to record a lap)\n", os.Getpid())
+		} else {
+			fmt.Fprintf(os.Stderr, "PID: %d (send SIGUSR1 to toggle pause, SIGUSR2 to skip)\n", os.Getpid())
+		}
 	}
 
 	// Parse comma-separated values
 	minutesList, err := parseIntList(*minutesStr)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing minutes: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	secondsList, err := parseIntList(*secondsStr)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing seconds: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
+	}
+
+	suspendPolicy, err := parseSuspendPolicy(*onSuspend)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	warnings, err := parseOffsets("warn", *warnStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	announcements, err := parseOffsets("announce", *announceStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	tickPeriods, err := parseMetronome(*metronomeStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if warnSound != WarnSoundTone && warnSound != WarnSoundSoft {
+		fmt.Fprintf(os.Stderr, "Error: invalid -warn-sound %q (want tone or soft)\n", warnSound)
+		os.Exit(exitError)
+	}
+	if playback.Policy != PolicyMix && playback.Policy != PolicyQueue && playback.Policy != PolicyCut {
+		fmt.Fprintf(os.Stderr, "Error: invalid -overlap %q (want mix, queue or cut)\n", playback.Policy)
+		os.Exit(exitError)
+	}
+	if playback.MaxSounds < 1 {
+		fmt.Fprintf(os.Stderr, "Error: -max-sounds must be at least 1\n")
+		os.Exit(exitError)
+	}
+	if *soundFile != "" {
+		data, err := os.ReadFile(*soundFile)
+		if err == nil {
+			beepSound, err = DecodeSound(data)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -sound %s: %v\n", *soundFile, err)
+			os.Exit(exitError)
+		}
+	} else if beepSound, err = DecodeSound(beepMP3); err != nil {
+		fmt.Fprintf(os.Stderr, "Error decoding beep: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	if *hookTimeout <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: -hook-timeout must be positive\n")
+		os.Exit(exitError)
+	}
+	if *alarmEvery < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -alarm must be positive\n")
+		os.Exit(exitError)
+	}
+	if (*alarmEscalate || *alarmHold) && *alarmEvery == 0 {
+		fmt.Fprintf(os.Stderr, "Error: -alarm-escalate and -alarm-hold need -alarm\n")
+		os.Exit(exitError)
+	}
+	if *alarmHold && *stopwatchMode {
+		fmt.Fprintf(os.Stderr, "Error: -alarm-hold can't be used with -stopwatch, which keeps counting\n")
+		os.Exit(exitError)
 	}
 
 	// Pad lists to equal length and build intervals
 	minutesList, secondsList = padLists(minutesList, secondsList)
 
-	intervals, err := buildIntervals(minutesList, secondsList)
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	var intervals []time.Duration
+	var beepEvery time.Duration
+	var schedule Schedule
+	var description string
+	if *daily && *until == "" {
+		fmt.Fprintf(os.Stderr, "Error: -daily requires -until\n")
+		os.Exit(exitError)
+	}
+	if *tz != "" && *until == "" && align.Cron == "" {
+		fmt.Fprintf(os.Stderr, "Error: -tz requires -until or an -align cron expression\n")
+		os.Exit(exitError)
+	}
+	if align.Enabled {
+		if *until != "" || *stopwatchMode {
+			fmt.Fprintf(os.Stderr, "Error: -align can't be combined with -until or -stopwatch\n")
+			os.Exit(exitError)
+		}
+		if align.Cron == "" && flag.NArg() > 0 {
+			// -align is a boolean flag, so a separate value ends flag parsing
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument %q, pass cron expressions as -align='%s'\n", flag.Arg(0), flag.Arg(0))
+			os.Exit(exitError)
+		}
+	}
+	loc := time.Local
+	if *tz != "" {
+		loc, err = time.LoadLocation(*tz)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid time zone: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+
+	if *until != "" {
+		if *stopwatchMode || setFlags["m"] || setFlags["s"] {
+			fmt.Fprintf(os.Stderr, "Error: -until can't be combined with -m, -s or -stopwatch\n")
+			os.Exit(exitError)
+		}
+		untilSchedule, err := ParseUntil(*until, time.Now(), loc, *daily)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		schedule = untilSchedule
+		description = untilSchedule.Describe()
+	} else if align.Cron != "" {
+		if setFlags["m"] || setFlags["s"] {
+			fmt.Fprintf(os.Stderr, "Error: an -align cron expression can't be combined with -m or -s\n")
+			os.Exit(exitError)
+		}
+		cron, err := ParseCron(align.Cron, loc)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		if cron.Next(time.Now()).IsZero() {
+			fmt.Fprintf(os.Stderr, "Error: cron expression %q never matches\n", cron.Expr)
+			os.Exit(exitError)
+		}
+		schedule = cron
+		description = cron.Describe()
+	} else if *stopwatchMode {
+		// The stopwatch only beeps if an interval was given
+		if len(minutesList) > 1 {
+			fmt.Fprintf(os.Stderr, "Error: -stopwatch takes a single beep interval\n")
+			os.Exit(exitError)
+		}
+		beepEvery = time.Duration(minutesList[0]*60+secondsList[0]) * time.Second
+		if beepEvery < 0 {
+			fmt.Fprintf(os.Stderr, "Error: beep interval must not be negative\n")
+			os.Exit(exitError)
+		}
+		intervals = []time.Duration{beepEvery}
+	} else {
+		intervals, err = buildIntervals(minutesList, secondsList)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	for _, cues := range []struct {
+		name   string
+		groups [][]time.Duration
+	}{{"warn", warnings}, {"announce", announcements}} {
+		name, groups := cues.name, cues.groups
+		if groups == nil {
+			continue
+		}
+		if *stopwatchMode && beepEvery == 0 {
+			fmt.Fprintf(os.Stderr, "Error: -%s needs a beep interval (-m or -s) in stopwatch mode\n", name)
+			os.Exit(exitError)
+		}
+		// Wall-clock schedules have a single interval of varying length
+		checked := intervals
+		if schedule != nil {
+			checked = []time.Duration{time.Duration(math.MaxInt64)}
+		}
+		if err := checkOffsets(name, groups, checked); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+
+	if tickPeriods != nil {
+		if *stopwatchMode && beepEvery == 0 {
+			fmt.Fprintf(os.Stderr, "Error: -metronome needs a beep interval (-m or -s) in stopwatch mode\n")
+			os.Exit(exitError)
+		}
+		if len(tickPeriods) > 1 && len(tickPeriods) > len(intervals) {
+			fmt.Fprintf(os.Stderr, "Error: -metronome has %d values but there are only %d intervals\n", len(tickPeriods), len(intervals))
+			os.Exit(exitError)
+		}
+	}
+
+	if *count < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -count must not be negative\n")
+		os.Exit(exitError)
+	}
+	if *runFor < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -for must not be negative\n")
+		os.Exit(exitError)
+	}
+	if *count > 0 && *stopwatchMode && beepEvery == 0 {
+		fmt.Fprintf(os.Stderr, "Error: -count needs a beep interval (-m or -s) in stopwatch mode\n")
+		os.Exit(exitError)
+	}
+
+	var tmpl *template.Template
+	if *formatStr != "" {
+		tmpl, err = parseFormatTemplate(*formatStr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing format: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+
+	var webhooks []*Webhook
+	if len(webhookURLs) > 0 {
+		var bodyTmpl *template.Template
+		if *webhookTemplate != "" {
+			bodyTmpl, err = parseWebhookTemplate(*webhookTemplate)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error parsing webhook template: %v\n", err)
+				os.Exit(exitError)
+			}
+		}
+		for _, url := range webhookURLs {
+			webhook := NewWebhook(url, bodyTmpl)
+			webhook.OnError = func(err error) {
+				logger.Error("sending webhook failed", "err", err)
+			}
+			webhooks = append(webhooks, webhook)
+		}
+	}
+
+	var notifier *Notifier
+	var notifyActions <-chan string
+	if *notify {
+		titleTmpl, err := parseNotifyTemplate("title", *notifyTitle)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing notification title: %v\n", err)
+			os.Exit(exitError)
+		}
+		bodyTmpl, err := parseNotifyTemplate("body", *notifyBody)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing notification body: %v\n", err)
+			os.Exit(exitError)
+		}
+		urgencies, err := parseUrgencies(*notifyUrgency)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing notification urgency: %v\n", err)
+			os.Exit(exitError)
+		}
+		conn, err := dbus.SessionBus()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error connecting to the session bus: %v\n", err)
+			os.Exit(exitError)
+		}
+		notifier, err = NewNotifier(conn, titleTmpl, bodyTmpl, urgencies)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		notifier.OnError = func(err error) {
+			logger.Error("sending notification failed", "err", err)
+		}
+		notifyActions = notifier.Actions()
+	}
+
+	if err := validateTerminalOptions(*oscNotify, *titleMode); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	term := &TerminalNotifier{Out: os.Stdout, Bell: *bell}
+	if *oscNotify != "off" {
+		term.OSC = *oscNotify
+	}
+	if *titleMode != "off" {
+		term.Title = *titleMode
+	}
+	if term.Enabled() && !isTerminal(os.Stdout) {
+		// Escape sequences would corrupt output read by other programs
+		term = &TerminalNotifier{}
+	}
+
+	// A socket passed by systemd socket activation takes the place of -http
+	listener, err := systemdListener()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
+	}
+	activated := listener != nil
+	if !activated && *httpAddr != "" {
+		listener, err = net.Listen("tcp", *httpAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error starting HTTP API: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+
+	var api *APIServer
+	var apiCommands <-chan Command
+	if listener != nil {
+		api = NewAPIServer()
+		api.Metrics = NewMetrics()
+		api.Addr = *httpAddr
+		if activated {
+			api.Addr = listener.Addr().String()
+		}
+		apiCommands = api.Commands()
+		reportAudioError := onAudioError
+		onAudioError = func(err error) {
+			api.Metrics.AudioError()
+			reportAudioError(err)
+		}
+		go func() {
+			if err := api.Server().Serve(listener); err != nil {
+				logger.Error("serving HTTP API failed", "err", err)
+			}
+		}()
 	}
 
 	// Initialize audio system
-	if err := initAudio(); err != nil {
+	deviceOpened := func() {}
+	if *device != "" {
+		deviceOpened, err = selectDevice(*device, listDevices())
+		if err != nil {
+			logger.Warn("using the default audio device", "device", *device, "err", err)
+		}
+	}
+	if err := initAudio(outputSampleRate(beepSound.SampleRate)); err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing audio: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
+	}
+	deviceOpened()
+
+	// Convert the beep now so the first one isn't late
+	beepPCM()
+
+	// Get the ticks ready so the first one is on time
+	if tickPeriods != nil {
+		tickSound.Prepare()
+		accentSound.Prepare()
+	}
+
+	// Load the voice clips and make sure every announcement can be spoken
+	if announcements != nil {
+		var voiceFS fs.FS = os.DirFS(*voiceDir)
+		if *voiceDir == "" {
+			voiceFS, _ = fs.Sub(voiceFiles, "voice")
+		}
+		voicePack, err = loadVoicePack(voiceFS, audioSampleRate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading voice clips: %v\n", err)
+			os.Exit(exitError)
+		}
+		// The built-in clips are never empty, so this is a wrong -voice
+		if len(voicePack) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: -voice %s holds no sound files (see voice/README.adoc)\n", *voiceDir)
+			os.Exit(exitError)
+		}
+		var missing []string
+		for _, group := range announcements {
+			for _, offset := range group {
+				missing = append(missing, voicePack.Missing(spokenTime(offset))...)
+			}
+		}
+		if len(missing) > 0 {
+			slices.Sort(missing)
+			fmt.Fprintf(os.Stderr, "Error: -announce needs voice clips for %s\n", strings.Join(slices.Compact(missing), ", "))
+			os.Exit(exitError)
+		}
 	}
 
 	// Verbose mode: show banner and instructions
-	if *verbose {
+	if *verbose && schedule != nil {
+		fmt.Printf("=== Interval Beeper ===\n")
+		fmt.Printf("%s.\n", description)
+		fmt.Printf("Press Ctrl+C to stop.\n\n")
+	} else if *verbose && *stopwatchMode {
+		fmt.Printf("=== Stopwatch ===\n")
+		if beepEvery > 0 {
+			fmt.Printf("Beeping every %d minutes %d seconds.\n", minutesList[0], secondsList[0])
+		}
+		if *interactive {
+			fmt.Printf("Press Enter to record a lap.\n")
+			fmt.Printf("Press Backspace to reset the stopwatch. Press Ctrl+C to stop.\n\n")
+		} else {
+			fmt.Printf("Press Ctrl+C to stop.\n\n")
+		}
+	} else if *verbose {
 		fmt.Printf("=== Interval Beeper ===\n")
 		if len(intervals) == 1 {
 			fmt.Printf("Beeping every %d minutes %d seconds.\n", minutesList[0], secondsList[0])
@@ -417,6 +1169,10 @@ func main() {
 				fmt.Printf("  %d. %dm %ds\n", i+1, minutesList[i], secondsList[i])
 			}
 		}
+		if align.Enabled {
+			_, first := alignIntervals(intervals, time.Now())
+			fmt.Printf("Aligned to the clock, first beep at %s.\n", first.Format("15:04:05"))
+		}
 		if *interactive {
 			fmt.Printf("Press Enter to beep immediately and reset timer.\n")
 			fmt.Printf("Press Backspace to reset timer silently. Press Ctrl+C to stop.\n\n")
@@ -448,191 +1204,732 @@ func main() {
 		}()
 	}
 
-	beepCount := 0
-	intervalIndex := 0
-	currentInterval := intervals[intervalIndex]
-	nextBeep := time.Now().Add(currentInterval)
+	// Channel to signal status bar clicks (i3blocks writes them to stdin)
+	clicked := make(chan bool)
+	if mode == ModeI3blocks && !*interactive {
+		go func() {
+			scanner := bufio.NewScanner(os.Stdin)
+			for scanner.Scan() {
+				if parseI3blocksClick(scanner.Text()) == 1 {
+					clicked <- true
+				}
+			}
+		}()
+	}
+
+	// Exactly one of state and sw is set
+	var state *TimerState
+	var sw *Stopwatch
+	switch {
+	case *stopwatchMode:
+		sw = NewStopwatch(beepEvery, *startPaused)
+	case schedule != nil:
+		state = NewScheduledTimerState(schedule, *startPaused)
+		intervals = state.Intervals
+		minutesList, secondsList = state.MinutesList, state.SecondsList
+	default:
+		state = NewTimerState(intervals, minutesList, secondsList, *startPaused)
+		if align.Enabled {
+			state.Aligned = true
+			state.alignToClock()
+		}
+	}
+	config := OutputConfig{
+		Mode:          mode,
+		MinutesList:   minutesList,
+		SecondsList:   secondsList,
+		IntervalCount: len(intervals),
+		Labels:        parseLabels(*labelsStr),
+		PID:           os.Getpid(),
+		Template:      tmpl,
+		Description:   description,
+		Hours:         *until != "",
+	}
+	// The ticker refreshes the display, beeps are timed separately so they
+	// aren't up to a tick late
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
+	beepTimer := time.NewTimer(0)
+	defer beepTimer.Stop()
+	// The cue timer plays warnings and announcements on time
+	cueTimer := time.NewTimer(0)
+	defer cueTimer.Stop()
+	clockWatch := NewClockWatch(1 * time.Second)
+
+	// Report to systemd when running as a Type=notify service
+	service, err := NewServiceNotifier()
+	if err != nil {
+		logger.Warn("systemd notifications disabled", "err", err)
+	}
+	var watchdog <-chan time.Time
+	if interval := watchdogInterval(); service != nil && interval > 0 {
+		watchdogTicker := time.NewTicker(interval / 2)
+		defer watchdogTicker.Stop()
+		watchdog = watchdogTicker.C
+	}
 
-	// Pause state
-	paused := *startPaused
-	pausedAt := time.Duration(0) // remaining time when paused
-
-	// Signal handling for SIGUSR1 (toggle pause)
+	// Signal handling for SIGUSR1 (toggle pause), SIGUSR2 (skip) and
+	// SIGINT/SIGTERM (stop)
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGUSR1)
-
-	// Helper function to output paused state
-	outputPaused := func() {
-		if *jsonMode {
-			output := WaybarOutput{
-				Text:      "Paused",
-				Tooltip:   "Click to start",
-				Class:     "paused",
-				Remaining: int(pausedAt.Seconds()),
-			}
-			jsonBytes, _ := json.Marshal(output)
-			fmt.Println(string(jsonBytes))
-		} else if *watchMode {
-			fmt.Println("PAUSED")
-		} else if *verbose {
-			fmt.Printf("\rPaused - %s remaining ", formatDuration(pausedAt))
+	skipChan := make(chan os.Signal, 1)
+	signal.Notify(skipChan, syscall.SIGUSR2)
+	stopChan := make(chan os.Signal, 1)
+	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
+
+	// Helper function to write formatted output based on mode
+	output := func(s string) {
+		if s == "" {
+			return
+		}
+		if mode.lineOriented() {
+			fmt.Println(s)
+		} else {
+			fmt.Print(s)
 			os.Stdout.Sync()
 		}
+		if mode == ModeTmux {
+			if err := writeStatusFile(*statusFile, s); err != nil {
+				logger.Error("writing status file failed", "err", err)
+			}
+		}
 	}
 
-	// Helper function to output tick based on mode
-	outputTick := func(remaining time.Duration) {
-		remainingSecs := int(remaining.Round(time.Second).Seconds())
-		if *jsonMode {
-			var tooltip string
-			if len(intervals) == 1 {
-				tooltip = fmt.Sprintf("%dm %ds", minutesList[0], secondsList[0])
-			} else {
-				tooltip = fmt.Sprintf("Interval %d/%d: %dm %ds", intervalIndex+1, len(intervals),
-					minutesList[intervalIndex], secondsList[intervalIndex])
-			}
-			output := WaybarOutput{
-				Text:      formatDuration(remaining),
-				Tooltip:   tooltip,
-				Class:     "counting",
-				Remaining: remainingSecs,
-			}
-			jsonBytes, _ := json.Marshal(output)
-			fmt.Println(string(jsonBytes))
-		} else if *watchMode {
-			fmt.Println(formatDuration(remaining))
-		} else if *verbose {
-			if len(intervals) == 1 {
-				fmt.Printf("\rNext beep in: %s ", formatDuration(remaining))
-			} else {
-				fmt.Printf("\rNext beep in: %s (interval %d/%d: %dm %ds) ",
-					formatDuration(remaining), intervalIndex+1, len(intervals),
-					minutesList[intervalIndex], secondsList[intervalIndex])
+	// Helper functions to format the timer or stopwatch state
+	isPaused := func() bool {
+		if sw != nil {
+			return sw.Paused
+		}
+		return state.Paused
+	}
+	limit := NewRunLimit(*count, *runFor, time.Now())
+	// The run timer ends a -for run on time, ticks check the wall clock as
+	// well since the timer doesn't count the time of a suspend
+	var runTimer <-chan time.Time
+	if d, ok := limit.Next(time.Now()); ok {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		runTimer = t.C
+	}
+	var alarm *Alarm
+	if *alarmEvery > 0 {
+		alarm = NewAlarm(*alarmEvery, *alarmEscalate, *alarmHold)
+	}
+	// alarmSounding reports whether the alarm waits to be acknowledged
+	alarmSounding := func() bool {
+		return alarm != nil && alarm.Active
+	}
+	var warner, announcer *Warner
+	if warnings != nil {
+		warner = NewWarner(warnings)
+	}
+	if announcements != nil {
+		announcer = NewWarner(announcements)
+	}
+	var metronome *Metronome
+	if tickPeriods != nil {
+		metronome = NewMetronome(tickPeriods)
+	}
+	// countdown returns the interval and the time to the next beep, which
+	// warnings, announcements and ticks are timed against
+	countdown := func() (int, time.Duration) {
+		if sw != nil {
+			return 0, sw.Remaining()
+		}
+		return state.IntervalIndex, state.Remaining()
+	}
+	templateData := func(s string) TemplateData {
+		var data TemplateData
+		if sw != nil {
+			data = NewStopwatchTemplateData(config, sw, s)
+		} else {
+			data = NewTemplateData(config, state, s)
+		}
+		if warner != nil {
+			data.Warning = warner.Active(countdown())
+		}
+		if alarmSounding() {
+			data.Alarm = true
+			data.AlarmBeeps = alarm.Beeps
+		}
+		return data
+	}
+	format := func(data TemplateData) string {
+		if sw != nil {
+			return FormatStopwatchOutput(config, data, sw.Laps, time.Now())
+		}
+		return FormatOutput(config, data, time.Now())
+	}
+
+	// Helper functions to create and publish timer events
+	newEvent := func(eventType EventType) Event {
+		var event Event
+		if sw != nil {
+			event = NewStopwatchEvent(eventType, config, sw, time.Now())
+		} else {
+			event = NewEvent(eventType, config, state, time.Now())
+		}
+		event.Alarm = alarmSounding()
+		return event
+	}
+	hooks := NewHooks(map[EventType]string{
+		EventBeep:         *onBeep,
+		EventPause:        *onPause,
+		EventResume:       *onResume,
+		EventSegmentStart: *onSegment,
+		EventWarning:      *onWarning,
+	}, *hookTimeout)
+	hooks.OnError = func(eventType EventType, err error) {
+		logger.Error("hook failed", "event", string(eventType), "err", err)
+	}
+	if api != nil {
+		reportHookError := hooks.OnError
+		hooks.OnError = func(eventType EventType, err error) {
+			api.Metrics.HookFailure()
+			reportHookError(eventType, err)
+		}
+	}
+	session := &Session{}
+	emit := func(event Event) {
+		session.Observe(event)
+		logEvent(logger, event)
+		if mode == ModeEvents {
+			fmt.Println(FormatEventOutput(event))
+		}
+		hooks.Run(event)
+		// Announce the interval by its label if there's a clip for it
+		if announcer != nil && event.Type == EventSegmentStart {
+			if label := strings.ToLower(event.Label); voicePack[label] != nil {
+				speak([]string{label})
 			}
-			os.Stdout.Sync()
 		}
-		// Default mode: no tick output
-	}
-
-	// Helper function to output beep based on mode
-	outputBeep := func(beepType string) {
-		if *jsonMode {
-			output := WaybarOutput{
-				Text:      "BEEP",
-				Tooltip:   fmt.Sprintf("Beep #%d (%s)", beepCount, beepType),
-				Class:     "beep",
-				Remaining: 0,
-			}
-			jsonBytes, _ := json.Marshal(output)
-			fmt.Println(string(jsonBytes))
-		} else if *watchMode {
-			fmt.Println("BEEP")
-		} else if *verbose {
-			if len(intervals) == 1 {
-				fmt.Printf("\r[%s] Beep #%d (%s)              \n", time.Now().Format("15:04:05"), beepCount, beepType)
+		for _, webhook := range webhooks {
+			webhook.Send(event)
+		}
+		if notifier != nil {
+			notifier.Send(event)
+		}
+		if term.Enabled() {
+			term.Handle(event)
+		}
+		if api != nil {
+			api.Metrics.Observe(event)
+			api.Publish(event)
+		}
+		if service != nil && event.Type != EventBeep && event.Type != EventSkip {
+			if err := service.Notify("STATUS=" + serviceStatus(event)); err != nil {
+				logger.Warn("notifying systemd failed", "err", err)
+			}
+		}
+	}
+
+	togglePause := func() {
+		var paused bool
+		if sw != nil {
+			paused = sw.TogglePause()
+		} else {
+			paused = state.TogglePause()
+		}
+		if paused {
+			emit(newEvent(EventPause))
+			if *verbose {
+				fmt.Printf("\r[%s] Paused                            \n", time.Now().Format("15:04:05"))
+				os.Stdout.Sync()
+			}
+			output(format(templateData("paused")))
+		} else {
+			emit(newEvent(EventResume))
+			if *verbose {
+				fmt.Printf("\r[%s] Resumed                           \n", time.Now().Format("15:04:05"))
+				os.Stdout.Sync()
+			}
+		}
+	}
+
+	// shutdown reports the session and exits once the sounds playing have
+	// ended and hooks, webhooks and notifications are delivered. Another
+	// stop signal exits right away.
+	shutdown := func(eventType EventType, reason string, code int) {
+		event := newEvent(eventType)
+		summary := session.Summary(reason, code, event.Timestamp)
+		event.Summary = &summary
+		emit(event)
+		switch mode {
+		case ModeDefault, ModeVerbose:
+			// End the countdown line, or the ^C echoed by the terminal
+			if mode == ModeVerbose || isTerminal(os.Stdout) {
+				fmt.Println()
+			}
+			fmt.Println(summary)
+		case ModeTmux:
+			// tmux shows nothing rather than a stale countdown
+			if err := os.Remove(*statusFile); err != nil && !os.IsNotExist(err) {
+				logger.Error("removing status file failed", "err", err)
+			}
+		}
+		if service != nil {
+			service.Notify("STOPPING=1")
+		}
+		go func() {
+			<-stopChan
+			term.Restore()
+			os.Exit(code)
+		}()
+
+		playback.Wait(beepWaitTimeout)
+		hooks.Wait()
+		for _, webhook := range webhooks {
+			webhook.Close(webhookCloseTimeout)
+		}
+		if notifier != nil {
+			notifier.Close()
+		}
+		term.Restore()
+		os.Exit(code)
+	}
+
+	// finish ends a timer whose schedule has no more beeps, or a -count or
+	// -for run
+	finish := func() {
+		output(format(templateData("finished")))
+		shutdown(EventFinish, ReasonFinished, exitOK)
+	}
+
+	beep := func(beepType string) {
+		// Automatic and missed beeps sound the alarm, manual ones don't. The
+		// last beep of a -count or -for run plays the done sound, its alarm
+		// repeats the beep.
+		last := beepType != "manual" && limit.Beep(time.Now())
+		switch {
+		case last:
+			playDone()
+			if alarm != nil {
+				alarm.Start(time.Now())
+			}
+		case alarm != nil && beepType != "manual":
+			playAlarm(alarm.Start(time.Now()))
+		default:
+			playBeep()
+		}
+
+		// The beep event describes the segment that just ended
+		event := newEvent(EventBeep)
+		event.BeepType = beepType
+		event.BeepCount++
+		event.Remaining = 0
+
+		if sw != nil {
+			// A manual beep doesn't move the stopwatch's beep schedule
+			if beepType != "manual" {
+				sw.TriggerBeep()
 			} else {
-				fmt.Printf("\r[%s] Beep #%d (%s) - next: %dm %ds     \n",
-					time.Now().Format("15:04:05"), beepCount, beepType,
-					minutesList[intervalIndex], secondsList[intervalIndex])
+				sw.BeepCount++
+			}
+			data := templateData("beep")
+			data.BeepType = beepType
+			output(format(data))
+			emit(event)
+			if last && !alarmSounding() {
+				finish()
 			}
+			return
+		}
+
+		state.TriggerBeep()
+		if alarmSounding() && alarm.Hold && !state.Paused {
+			// Hold the next interval until the alarm is acknowledged
+			state.TogglePause()
+		}
+		data := templateData("beep")
+		data.BeepType = beepType
+		output(format(data))
+		emit(event)
+		if state.Done || last {
+			// The last beep finishes once its alarm is acknowledged
+			if alarmSounding() {
+				return
+			}
+			finish()
+		}
+		emit(newEvent(EventSegmentStart))
+	}
+
+	// endRun ends a -for run whose time is up. A beep due right now is its
+	// last beep, otherwise the done sound plays on its own.
+	endRun := func() {
+		if limit.Reached {
+			// The last beep was played, its alarm waits to be acknowledged
+			return
+		}
+		_, remaining := countdown()
+		if !isPaused() && (sw == nil || sw.Every > 0) && remaining <= runLimitSlack {
+			beep("automatic")
+			return
+		}
+		playDone()
+		finish()
+	}
+
+	// acknowledge silences the alarm and starts a held interval
+	acknowledge := func() {
+		sounded := alarm.Acknowledge(time.Now())
+		if *verbose {
+			fmt.Printf("\r[%s] Alarm acknowledged after %s                \n", time.Now().Format("15:04:05"), formatDuration(sounded))
 			os.Stdout.Sync()
+		}
+		if alarm.Hold && state.Paused && !state.Done {
+			state.TogglePause()
+		}
+		event := newEvent(EventAcknowledge)
+		event.AlarmBeeps = alarm.Beeps
+		emit(event)
+		if state != nil && state.Done || limit.Reached {
+			finish()
+		}
+		if isPaused() {
+			output(format(templateData("paused")))
 		} else {
-			// Default mode: simple beep line
-			fmt.Printf("BEEP %s\n", time.Now().Format(time.RFC3339))
+			output(format(templateData("counting")))
 		}
 	}
 
-	// Helper function to output reset (verbose only)
-	outputReset := func() {
+	// pauseOrAcknowledge is the action of SIGUSR1 and status bar clicks,
+	// which acknowledge a sounding alarm
+	pauseOrAcknowledge := func() {
+		if alarmSounding() {
+			acknowledge()
+			return
+		}
+		togglePause()
+	}
+
+	lap := func() {
+		recorded := sw.Lap()
+		output(format(templateData("lap")))
+		// The lap event describes the lap that was just recorded
+		event := newEvent(EventLap)
+		event.Lap = recorded.Number
+		event.LapTime = int(recorded.Time.Round(time.Second).Seconds())
+		emit(event)
+	}
+
+	reset := func() {
+		if sw != nil {
+			sw.Reset()
+		} else {
+			state.ResetTimer()
+		}
+		output(format(templateData("reset")))
+		emit(newEvent(EventReset))
+	}
+
+	skip := func() {
+		if sw != nil {
+			lap()
+			return
+		}
+		event := newEvent(EventSkip) // describes the skipped segment
+		state.Skip()
+		if state.Done {
+			emit(event)
+			finish()
+		}
 		if *verbose {
-			if len(intervals) == 1 {
-				fmt.Printf("\r[%s] Timer reset (silent)              \n", time.Now().Format("15:04:05"))
-			} else {
-				fmt.Printf("\r[%s] Timer reset (silent) - interval %d/%d: %dm %ds      \n",
-					time.Now().Format("15:04:05"), intervalIndex+1, len(intervals),
-					minutesList[intervalIndex], secondsList[intervalIndex])
+			fmt.Printf("\r[%s] Skipped to interval %d/%d                \n", time.Now().Format("15:04:05"),
+				state.IntervalIndex+1, len(intervals))
+			os.Stdout.Sync()
+		}
+		if state.Paused {
+			output(format(templateData("paused")))
+		}
+		emit(event)
+		emit(newEvent(EventSegmentStart))
+	}
+
+	// armBeep sets the beep timer to the next beep
+	armBeep := func() {
+		if isPaused() || sw != nil && sw.Every == 0 || state != nil && state.Done || limit.Reached {
+			beepTimer.Stop()
+			return
+		}
+		if sw != nil {
+			beepTimer.Reset(sw.Remaining())
+		} else {
+			beepTimer.Reset(max(state.Remaining(), 0))
+		}
+	}
+
+	// armCues sets the cue timer to the next warning, announcement,
+	// metronome tick or alarm repeat
+	armCues := func() {
+		cueTimer.Stop()
+		var next time.Duration
+		armed := false
+		if alarm != nil {
+			next, armed = alarm.Next(time.Now())
+		}
+		if isPaused() {
+			if armed {
+				cueTimer.Reset(next)
+			}
+			return
+		}
+		for _, w := range []*Warner{warner, announcer} {
+			if w == nil {
+				continue
 			}
+			if d, ok := w.Next(countdown()); ok && (!armed || d < next) {
+				next, armed = d, true
+			}
+		}
+		if metronome != nil {
+			if d, ok := metronome.Next(countdown()); ok && (!armed || d < next) {
+				next, armed = d, true
+			}
+		}
+		if armed {
+			cueTimer.Reset(next)
+		}
+	}
+
+	// checkCues repeats a sounding alarm, and plays a metronome tick,
+	// warning or announcement when the countdown reached one
+	checkCues := func() {
+		segment, remaining := countdown()
+		now := time.Now()
+		if metronome != nil {
+			if accent, ok := metronome.Check(segment, remaining, now); ok {
+				playTick(accent)
+			}
+		}
+		if alarm != nil {
+			if volume, ok := alarm.Repeat(now); ok {
+				playAlarm(volume)
+				output(format(templateData("alarm")))
+				event := newEvent(EventAlarm)
+				event.AlarmBeeps = alarm.Beeps
+				emit(event)
+			}
+		}
+		if announcer != nil {
+			if offset, ok := announcer.Check(segment, remaining, now); ok {
+				speak(spokenTime(offset))
+			}
+		}
+		if warner == nil {
+			return
+		}
+		if _, ok := warner.Check(segment, remaining, now); !ok {
+			return
+		}
+		playWarning()
+		output(format(templateData("warning")))
+		emit(newEvent(EventWarning))
+	}
+
+	// checkClock applies the -on-suspend policy after a suspend or clock change
+	checkClock := func() {
+		jump, ok := clockWatch.Check(time.Now())
+		if !ok {
+			return
+		}
+		var missed bool
+		if sw != nil {
+			missed = sw.ClockJumped(jump, suspendPolicy)
+		} else {
+			missed = state.ClockJumped(jump, suspendPolicy)
+		}
+		event := newEvent(EventClockJump)
+		event.Jump = int(jump.Jump().Round(time.Second).Seconds())
+		event.Policy = string(suspendPolicy)
+		event.Missed = missed
+		emit(event)
+		if *verbose {
+			fmt.Printf("\r[%s] Clock jumped by %s                \n", time.Now().Format("15:04:05"), formatDuration(jump.Jump().Abs()))
 			os.Stdout.Sync()
 		}
-		// Other modes: silent reset is truly silent
+		if !missed {
+			return
+		}
+
+		// Wall-clock timers can't stop the clock, so pause acts like skip
+		if suspendPolicy == SuspendFire {
+			beep("missed")
+			return
+		}
+		if sw != nil {
+			sw.SkipMissed()
+			return
+		}
+		// The clock-jump event reports the missed beep, a skip event would
+		// count as skipped by the user
+		state.SkipMissed()
+		if state.Done {
+			finish()
+		}
+		emit(newEvent(EventSegmentStart))
 	}
 
-	// If starting paused, set initial pausedAt
-	if paused {
-		pausedAt = currentInterval
-		outputPaused()
+	// handleCommand applies a control command from the HTTP API
+	handleCommand := func(cmd Command) error {
+		switch cmd.Name {
+		case CommandPause:
+			if isPaused() {
+				return errAlreadyPaused
+			}
+			togglePause()
+		case CommandResume:
+			// Resuming a held interval acknowledges its alarm
+			if alarmSounding() && alarm.Hold {
+				acknowledge()
+				return nil
+			}
+			if !isPaused() {
+				return errAlreadyRunning
+			}
+			togglePause()
+		case CommandSkip:
+			if sw != nil {
+				return errStopwatch
+			}
+			skip()
+		case CommandLap:
+			if sw == nil {
+				return errNotStopwatch
+			}
+			lap()
+		case CommandAck:
+			if !alarmSounding() {
+				return errNoAlarm
+			}
+			acknowledge()
+		case CommandReset:
+			reset()
+		case CommandBeep:
+			if isPaused() {
+				return errPaused
+			}
+			beep("manual")
+		case CommandTime:
+			if sw != nil {
+				return errStopwatch
+			}
+			state.SetRemaining(cmd.Remaining)
+			if state.Paused {
+				output(format(templateData("paused")))
+			} else {
+				output(format(templateData("counting")))
+			}
+			emit(newEvent(EventTick))
+		default:
+			return fmt.Errorf("unknown command %q", cmd.Name)
+		}
+		return nil
+	}
+
+	emit(newEvent(EventStart))
+	emit(newEvent(EventSegmentStart))
+
+	// If starting paused, show the paused state right away
+	if isPaused() {
+		output(format(templateData("paused")))
+	}
+
+	if service != nil {
+		if err := service.Notify("READY=1"); err != nil {
+			logger.Warn("notifying systemd failed", "err", err)
+		}
 	}
 
 	for {
+		// Checking after every change lets the next check see where the
+		// countdown went on from, so the first cue after a start, beep or
+		// skip isn't missed
+		checkCues()
+		armBeep()
+		armCues()
 		select {
+		case sig := <-stopChan:
+			code, reason := signalExitCode(sig)
+			shutdown(EventStop, reason, code)
+
 		case <-sigChan:
-			// Toggle pause state
-			if paused {
-				// Resume: set nextBeep based on remaining time
-				paused = false
-				nextBeep = time.Now().Add(pausedAt)
-				if *verbose {
-					fmt.Printf("\r[%s] Resumed                           \n", time.Now().Format("15:04:05"))
-					os.Stdout.Sync()
-				}
-			} else {
-				// Pause: save remaining time
-				paused = true
-				pausedAt = time.Until(nextBeep)
-				if pausedAt < 0 {
-					pausedAt = 0
-				}
-				if *verbose {
-					fmt.Printf("\r[%s] Paused                            \n", time.Now().Format("15:04:05"))
-					os.Stdout.Sync()
+			pauseOrAcknowledge()
+
+		case <-clicked:
+			pauseOrAcknowledge()
+
+		case <-skipChan:
+			skip()
+
+		case action := <-notifyActions:
+			switch action {
+			case ActionPause:
+				if alarmSounding() {
+					acknowledge()
+				} else if !isPaused() {
+					togglePause()
 				}
-				outputPaused()
+			case ActionSkip:
+				skip()
 			}
 
-		case <-ticker.C:
-			if paused {
-				outputPaused()
-				continue
+		case <-watchdog:
+			if err := service.Notify("WATCHDOG=1"); err != nil {
+				logger.Warn("notifying systemd failed", "err", err)
 			}
 
-			remaining := time.Until(nextBeep)
+		case cmd := <-apiCommands:
+			cmd.Reply(handleCommand(cmd))
 
-			if remaining <= 0 {
-				beepCount++
-				playBeep()
+		case <-runTimer:
+			endRun()
 
-				// Move to next interval in the rotation
-				intervalIndex = (intervalIndex + 1) % len(intervals)
-				currentInterval = intervals[intervalIndex]
+		case <-ticker.C:
+			checkClock()
+			if limit.Expired(time.Now()) {
+				endRun()
+			}
+			checkCues()
+			if isPaused() {
+				output(format(templateData("paused")))
+				continue
+			}
+			output(format(templateData("counting")))
+			emit(newEvent(EventTick))
 
-				outputBeep("automatic")
-				nextBeep = time.Now().Add(currentInterval)
-			} else {
-				outputTick(remaining)
+		case <-cueTimer.C:
+			checkClock()
+			checkCues()
+
+		case <-beepTimer.C:
+			checkClock()
+			if isPaused() {
+				continue
+			}
+			if sw != nil && sw.BeepDue() || sw == nil && state.Remaining() <= 0 {
+				beep("automatic")
 			}
 
 		case <-enterPressed:
-			if paused {
+			if alarmSounding() {
+				acknowledge()
 				continue
 			}
-			beepCount++
-			playBeep()
-
-			// Move to next interval in the rotation
-			intervalIndex = (intervalIndex + 1) % len(intervals)
-			currentInterval = intervals[intervalIndex]
-
-			outputBeep("manual")
-			nextBeep = time.Now().Add(currentInterval)
+			if isPaused() {
+				continue
+			}
+			if sw != nil {
+				lap()
+				continue
+			}
+			beep("manual")
 
 		case <-backspacePressed:
-			if paused {
+			if isPaused() {
 				continue
 			}
-			outputReset()
-			nextBeep = time.Now().Add(currentInterval)
+			reset()
 		}
 	}
 }
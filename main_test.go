@@ -8,8 +8,13 @@ import (
 
 // TestMain sets up test environment to prevent sound playback
 func TestMain(m *testing.M) {
-	// Replace beepFunc with a no-op to prevent sound during tests
+	// Replace the sound functions with no-ops to prevent sound during tests
 	beepFunc = func() {}
+	warnFunc = func() {}
+	speakFunc = func([]string) {}
+	alarmFunc = func(float64) {}
+	tickFunc = func(bool) {}
+	doneFunc = func() {}
 	m.Run()
 }
 
@@ -55,6 +60,11 @@ func TestFormatDuration(t *testing.T) {
 			duration: 59 * time.Second,
 			expected: "59s",
 		},
+		{
+			name:     "stays in minutes past an hour",
+			duration: 90 * time.Minute,
+			expected: "90m 0s",
+		},
 	}
 
 	for _, tt := range tests {
@@ -67,6 +77,35 @@ func TestFormatDuration(t *testing.T) {
 	}
 }
 
+// TestFormatLongDuration tests the hours format of -until output
+func TestFormatLongDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{45 * time.Second, "45s"},
+		{59*time.Minute + 59*time.Second, "59m 59s"},
+		{time.Hour, "1h 0m 0s"},
+		{2*time.Hour + 5*time.Minute + 3*time.Second, "2h 5m 3s"},
+	}
+
+	for _, tt := range tests {
+		if result := formatLongDuration(tt.duration); result != tt.expected {
+			t.Errorf("formatLongDuration(%v) = %q, want %q", tt.duration, result, tt.expected)
+		}
+	}
+
+	// Only -until output uses hours
+	config := OutputConfig{Mode: ModeWatch, MinutesList: []int{90}, SecondsList: []int{0}, IntervalCount: 1}
+	if result := FormatTickOutput(config, 90*time.Minute, 0); result != "90m 0s" {
+		t.Errorf("FormatTickOutput() = %q, want 90m 0s", result)
+	}
+	config.Hours = true
+	if result := FormatTickOutput(config, 90*time.Minute, 0); result != "1h 30m 0s" {
+		t.Errorf("FormatTickOutput() with Hours = %q, want 1h 30m 0s", result)
+	}
+}
+
 // TestParseIntList tests the parseIntList function
 func TestParseIntList(t *testing.T) {
 	tests := []struct {
@@ -139,6 +178,30 @@ func TestParseIntList(t *testing.T) {
 	}
 }
 
+// TestParseLabels tests the parseLabels and labelFor functions
+func TestParseLabels(t *testing.T) {
+	labels := parseLabels("work, break ,long break")
+	expected := []string{"work", "break", "long break"}
+	if len(labels) != len(expected) {
+		t.Fatalf("parseLabels() returned %d labels, want %d", len(labels), len(expected))
+	}
+	for i := range expected {
+		if labels[i] != expected[i] {
+			t.Errorf("labels[%d] = %q, want %q", i, labels[i], expected[i])
+		}
+	}
+
+	if parseLabels("") != nil {
+		t.Error("parseLabels(\"\") should return nil")
+	}
+	if labelFor(labels, 1) != "break" {
+		t.Errorf("labelFor(1) = %q, want %q", labelFor(labels, 1), "break")
+	}
+	if labelFor(labels, 5) != "" {
+		t.Errorf("labelFor(5) = %q, want empty", labelFor(labels, 5))
+	}
+}
+
 // TestPadLists tests the padLists function
 func TestPadLists(t *testing.T) {
 	tests := []struct {
@@ -559,6 +622,27 @@ func TestTimerStateTriggerBeep(t *testing.T) {
 	}
 }
 
+// TestTimerStateTriggerBeepDriftFree tests that a beep handled a little late
+// doesn't delay the following ones
+func TestTimerStateTriggerBeepDriftFree(t *testing.T) {
+	intervals := []time.Duration{time.Minute}
+	ts := NewTimerState(intervals, []int{1}, []int{0}, false)
+
+	due := time.Now().Add(-300 * time.Millisecond)
+	ts.NextBeep = due
+	ts.TriggerBeep()
+	if want := due.Add(time.Minute); !ts.NextBeep.Equal(want) {
+		t.Errorf("NextBeep = %v, want %v", ts.NextBeep, want)
+	}
+
+	// A beep that is far too late starts the next interval now
+	ts.NextBeep = time.Now().Add(-time.Hour)
+	ts.TriggerBeep()
+	if remaining := ts.Remaining(); remaining <= 59*time.Second || remaining > time.Minute {
+		t.Errorf("Remaining() = %v, expected about 1m", remaining)
+	}
+}
+
 // TestTimerStateResetTimer tests the ResetTimer method
 func TestTimerStateResetTimer(t *testing.T) {
 	intervals := []time.Duration{25 * time.Minute}
@@ -574,6 +658,55 @@ func TestTimerStateResetTimer(t *testing.T) {
 	}
 }
 
+// TestTimerStateSkip tests the Skip method
+func TestTimerStateSkip(t *testing.T) {
+	intervals := []time.Duration{25 * time.Minute, 5 * time.Minute}
+
+	t.Run("running", func(t *testing.T) {
+		ts := NewTimerState(intervals, []int{25, 5}, []int{0, 0}, false)
+		ts.Skip()
+
+		if ts.IntervalIndex != 1 {
+			t.Errorf("IntervalIndex = %d, want 1", ts.IntervalIndex)
+		}
+		if ts.BeepCount != 0 {
+			t.Errorf("BeepCount = %d, want 0 (skip must not count as a beep)", ts.BeepCount)
+		}
+		if remaining := ts.Remaining(); remaining <= 4*time.Minute || remaining > 5*time.Minute {
+			t.Errorf("Remaining() = %v, expected about 5 minutes", remaining)
+		}
+	})
+
+	t.Run("paused", func(t *testing.T) {
+		ts := NewTimerState(intervals, []int{25, 5}, []int{0, 0}, true)
+		ts.Skip()
+
+		if !ts.Paused {
+			t.Error("timer should stay paused after skip")
+		}
+		if ts.PausedAt != 5*time.Minute {
+			t.Errorf("PausedAt = %v, want 5m", ts.PausedAt)
+		}
+	})
+}
+
+// TestTimerStateSetRemaining tests the SetRemaining method
+func TestTimerStateSetRemaining(t *testing.T) {
+	intervals := []time.Duration{25 * time.Minute}
+
+	ts := NewTimerState(intervals, []int{25}, []int{0}, false)
+	ts.SetRemaining(90 * time.Second)
+	if remaining := ts.Remaining(); remaining <= 89*time.Second || remaining > 90*time.Second {
+		t.Errorf("Remaining() = %v, expected about 90s", remaining)
+	}
+
+	ts = NewTimerState(intervals, []int{25}, []int{0}, true)
+	ts.SetRemaining(90 * time.Second)
+	if ts.Remaining() != 90*time.Second {
+		t.Errorf("Remaining() = %v, want 90s while paused", ts.Remaining())
+	}
+}
+
 // TestTimerStateRemaining tests the Remaining method
 func TestTimerStateRemaining(t *testing.T) {
 	intervals := []time.Duration{1 * time.Minute}
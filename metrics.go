@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// beepTypes are always exported so rate() works before the first beep
+var beepTypes = []string{"automatic", "manual", "missed"}
+
+// intervalKey identifies an interval for the running time counter
+type intervalKey struct {
+	Segment int
+	Label   string
+}
+
+// Metrics collects timer statistics and serves them in the Prometheus text
+// exposition format. It is fed with the timer events and may be used from
+// several goroutines.
+type Metrics struct {
+	mu           sync.Mutex
+	beeps        map[string]uint64
+	pauses       uint64
+	resets       uint64
+	skips        uint64
+	audioErrors  uint64
+	hookFailures uint64
+	clockJumps   uint64
+	warnings     uint64
+	running      map[intervalKey]float64 // seconds counted down per interval
+	latest       Event
+	counted      time.Time // running time is credited up to here
+	now          func() time.Time
+}
+
+// NewMetrics creates an empty metrics collector
+func NewMetrics() *Metrics {
+	return &Metrics{
+		beeps:   make(map[string]uint64),
+		running: make(map[intervalKey]float64),
+		now:     time.Now,
+	}
+}
+
+// Observe updates the metrics with a timer event
+func (m *Metrics) Observe(event Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addRunning(event.Timestamp)
+	switch event.Type {
+	case EventBeep:
+		m.beeps[event.BeepType]++
+	case EventPause:
+		m.pauses++
+	case EventReset:
+		m.resets++
+	case EventSkip:
+		m.skips++
+	case EventClockJump:
+		m.clockJumps++
+	case EventWarning:
+		m.warnings++
+	}
+	m.latest = event
+	if event.Timestamp.After(m.counted) {
+		m.counted = event.Timestamp
+	}
+}
+
+// addRunning credits the time since the latest event to its interval, unless
+// the timer was paused
+func (m *Metrics) addRunning(now time.Time) {
+	if m.latest.Type == "" || m.latest.Paused {
+		return
+	}
+	if elapsed := now.Sub(m.counted).Seconds(); elapsed > 0 {
+		m.running[intervalKey{m.latest.Segment, m.latest.Label}] += elapsed
+		m.counted = now
+	}
+}
+
+// AudioError counts a beep that could not be played
+func (m *Metrics) AudioError() {
+	m.mu.Lock()
+	m.audioErrors++
+	m.mu.Unlock()
+}
+
+// HookFailure counts a hook command that failed or timed out
+func (m *Metrics) HookFailure() {
+	m.mu.Lock()
+	m.hookFailures++
+	m.mu.Unlock()
+}
+
+// ServeHTTP writes the metrics for a Prometheus scrape
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Bring the running time up to date, the counter never goes backwards
+	// because the next event only adds the time after now
+	m.addRunning(m.now())
+
+	var b strings.Builder
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("bleep_beeps_total", "counter", "Beeps by type.")
+	types := append([]string(nil), beepTypes...)
+	for beepType := range m.beeps {
+		if !slices.Contains(types, beepType) {
+			types = append(types, beepType)
+		}
+	}
+	for _, beepType := range types {
+		fmt.Fprintf(&b, "bleep_beeps_total{type=\"%s\"} %d\n", escapeLabelValue(beepType), m.beeps[beepType])
+	}
+
+	metric("bleep_pauses_total", "counter", "Times the timer was paused.")
+	fmt.Fprintf(&b, "bleep_pauses_total %d\n", m.pauses)
+	metric("bleep_resets_total", "counter", "Times the current interval was restarted.")
+	fmt.Fprintf(&b, "bleep_resets_total %d\n", m.resets)
+	metric("bleep_skips_total", "counter", "Intervals skipped without a beep.")
+	fmt.Fprintf(&b, "bleep_skips_total %d\n", m.skips)
+	metric("bleep_audio_errors_total", "counter", "Beeps that could not be played.")
+	fmt.Fprintf(&b, "bleep_audio_errors_total %d\n", m.audioErrors)
+	metric("bleep_hook_failures_total", "counter", "Hook commands that failed or timed out.")
+	fmt.Fprintf(&b, "bleep_hook_failures_total %d\n", m.hookFailures)
+	metric("bleep_clock_jumps_total", "counter", "Suspends and clock changes detected.")
+	fmt.Fprintf(&b, "bleep_clock_jumps_total %d\n", m.clockJumps)
+	metric("bleep_warnings_total", "counter", "Warnings played before beeps.")
+	fmt.Fprintf(&b, "bleep_warnings_total %d\n", m.warnings)
+
+	metric("bleep_running_seconds_total", "counter", "Time counted down while not paused, by interval.")
+	keys := make([]intervalKey, 0, len(m.running))
+	for key := range m.running {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Segment < keys[j].Segment })
+	for _, key := range keys {
+		fmt.Fprintf(&b, "bleep_running_seconds_total{interval=\"%d\",label=\"%s\"} %.3f\n",
+			key.Segment, escapeLabelValue(key.Label), m.running[key])
+	}
+
+	remaining := m.latest.Remaining
+	if !m.latest.Paused && m.latest.Type != "" {
+		// Events carry the remaining time when they happened
+		remaining -= int(m.now().Sub(m.latest.Timestamp).Seconds())
+		if remaining < 0 {
+			remaining = 0
+		}
+	}
+	paused := 0
+	if m.latest.Paused {
+		paused = 1
+	}
+
+	metric("bleep_remaining_seconds", "gauge", "Time left in the current interval.")
+	fmt.Fprintf(&b, "bleep_remaining_seconds %d\n", remaining)
+	metric("bleep_interval", "gauge", "Current interval, starting at 1.")
+	fmt.Fprintf(&b, "bleep_interval %d\n", m.latest.Segment)
+	metric("bleep_intervals", "gauge", "Number of intervals in the rotation.")
+	fmt.Fprintf(&b, "bleep_intervals %d\n", m.latest.Segments)
+	metric("bleep_paused", "gauge", "1 if the timer is paused, 0 otherwise.")
+	fmt.Fprintf(&b, "bleep_paused %d\n", paused)
+	metric("bleep_info", "gauge", "Build information.")
+	fmt.Fprintf(&b, "bleep_info{version=\"%s\"} 1\n", escapeLabelValue(version))
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// escapeLabelValue escapes a Prometheus label value
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMetrics tests the counters and gauges derived from events
+func TestMetrics(t *testing.T) {
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	now := start
+	m := NewMetrics()
+	m.now = func() time.Time { return now }
+
+	at := func(seconds int, event Event) Event {
+		event.Timestamp = start.Add(time.Duration(seconds) * time.Second)
+		event.Segments = 2
+		return event
+	}
+	m.Observe(at(0, Event{Type: EventStart, Segment: 1, Label: "work", Remaining: 60}))
+	m.Observe(at(0, Event{Type: EventSegmentStart, Segment: 1, Label: "work", Remaining: 60}))
+	m.Observe(at(60, Event{Type: EventBeep, BeepType: "automatic", Segment: 1, Label: "work", BeepCount: 1}))
+	m.Observe(at(60, Event{Type: EventSegmentStart, Segment: 2, Label: "break", Remaining: 30, BeepCount: 1}))
+	m.Observe(at(70, Event{Type: EventPause, Segment: 2, Label: "break", Remaining: 20, BeepCount: 1, Paused: true}))
+	m.Observe(at(100, Event{Type: EventResume, Segment: 2, Label: "break", Remaining: 20, BeepCount: 1}))
+	m.Observe(at(105, Event{Type: EventReset, Segment: 2, Label: "break", Remaining: 30, BeepCount: 1}))
+	m.AudioError()
+	m.HookFailure()
+	m.HookFailure()
+	now = start.Add(110 * time.Second)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	tests := []string{
+		`bleep_beeps_total{type="automatic"} 1`,
+		`bleep_beeps_total{type="manual"} 0`,
+		`bleep_pauses_total 1`,
+		`bleep_resets_total 1`,
+		`bleep_skips_total 0`,
+		`bleep_audio_errors_total 1`,
+		`bleep_hook_failures_total 2`,
+		`bleep_running_seconds_total{interval="1",label="work"} 60.000`,
+		// 10s before the pause and 10s after resuming
+		`bleep_running_seconds_total{interval="2",label="break"} 20.000`,
+		// 30s at the reset, 5s ago
+		`bleep_remaining_seconds 25`,
+		`bleep_interval 2`,
+		`bleep_intervals 2`,
+		`bleep_paused 0`,
+		"# TYPE bleep_beeps_total counter",
+		"# TYPE bleep_paused gauge",
+	}
+	for _, want := range tests {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("metrics missing %q\n%s", want, body)
+		}
+	}
+
+	// A later event only adds the time after the scrape
+	m.Observe(at(115, Event{Type: EventPause, Segment: 2, Label: "break", Remaining: 20, BeepCount: 1, Paused: true}))
+	now = start.Add(200 * time.Second)
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`bleep_running_seconds_total{interval="2",label="break"} 25.000`,
+		`bleep_remaining_seconds 20`,
+		`bleep_paused 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want+"\n") {
+			t.Errorf("metrics missing %q\n%s", want, rec.Body.String())
+		}
+	}
+}
+
+// TestEscapeLabelValue tests the escapeLabelValue function
+func TestEscapeLabelValue(t *testing.T) {
+	if got := escapeLabelValue("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("escapeLabelValue() = %q", got)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxTempo is the fastest -metronome tempo in beats per minute
+	maxTempo = 600
+	// tickAccentWindow is how close to the beep ticks are accented, to
+	// count down the last seconds
+	tickAccentWindow = 5 * time.Second
+)
+
+// parseMetronome parses -metronome: comma-separated per interval, a tempo
+// in beats per minute, "on" for a tick every second or "off". Returns the
+// time between ticks per interval, 0 for none.
+func parseMetronome(s string) ([]time.Duration, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var periods []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "on":
+			periods = append(periods, time.Second)
+			continue
+		case "off", "":
+			periods = append(periods, 0)
+			continue
+		}
+		bpm, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -metronome value %q (want a tempo in BPM, on or off)", part)
+		}
+		if bpm < 1 || bpm > maxTempo {
+			return nil, fmt.Errorf("-metronome tempo %d is out of range (1-%d BPM)", bpm, maxTempo)
+		}
+		periods = append(periods, time.Minute/time.Duration(bpm))
+	}
+	return periods, nil
+}
+
+// Metronome decides when to tick during an interval. Ticks fall on whole
+// periods before the beep, so the last one comes a period before it and
+// they line up with the countdown; the beep itself doesn't tick.
+type Metronome struct {
+	Periods []time.Duration // per interval, 0 for none; a single period applies to all
+
+	prev countdownCheck
+}
+
+// NewMetronome creates a metronome for the parsed -metronome periods
+func NewMetronome(periods []time.Duration) *Metronome {
+	return &Metronome{Periods: periods}
+}
+
+// For returns the time between ticks in an interval, 0 for none
+func (m *Metronome) For(segment int) time.Duration {
+	if len(m.Periods) == 1 {
+		return m.Periods[0]
+	}
+	if segment < len(m.Periods) {
+		return m.Periods[segment]
+	}
+	return 0
+}
+
+// Next returns the time until the next tick of the interval, or false if
+// none is left
+func (m *Metronome) Next(segment int, remaining time.Duration) (time.Duration, bool) {
+	period := m.For(segment)
+	if period == 0 || remaining <= period {
+		return 0, false
+	}
+	// The last tick before the countdown reaches remaining
+	k := (remaining - 1) / period
+	return remaining - k*period, true
+}
+
+// Check reports whether a tick was reached since the last check and whether
+// it is accented. Several ticks reached at once sound as one.
+func (m *Metronome) Check(segment int, remaining time.Duration, now time.Time) (accent, ok bool) {
+	prev := m.prev
+	m.prev = countdownCheck{segment, remaining, now}
+	period := m.For(segment)
+	if period == 0 || !prev.countedTo(m.prev) {
+		return false, false
+	}
+	// Ticks at k periods before the beep with remaining <= k*period <
+	// prev.remaining; k = 0 is the beep
+	high := (prev.remaining - 1) / period
+	low := max((remaining+period-1)/period, 1)
+	if high < low {
+		return false, false
+	}
+	return low*period <= tickAccentWindow, true
+}
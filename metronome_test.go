@@ -0,0 +1,98 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestParseMetronome tests parsing -metronome values
+func TestParseMetronome(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []time.Duration
+		wantErr  bool
+	}{
+		{"", nil, false},
+		{"on", []time.Duration{time.Second}, false},
+		{"120", []time.Duration{500 * time.Millisecond}, false},
+		{"on, off", []time.Duration{time.Second, 0}, false},
+		{"90,,off", []time.Duration{time.Minute / 90, 0, 0}, false},
+		{"fast", nil, true},
+		{"0", nil, true},
+		{"601", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := parseMetronome(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMetronome(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !slices.Equal(result, tt.expected) {
+				t.Errorf("parseMetronome(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestMetronomeNext tests the time to the next tick
+func TestMetronomeNext(t *testing.T) {
+	m := NewMetronome([]time.Duration{500 * time.Millisecond, 0})
+	tests := []struct {
+		name      string
+		segment   int
+		remaining time.Duration
+		expected  time.Duration
+		ok        bool
+	}{
+		{"between ticks", 0, 2300 * time.Millisecond, 300 * time.Millisecond, true},
+		{"on a tick", 0, 2 * time.Second, 500 * time.Millisecond, true},
+		{"last tick", 0, 600 * time.Millisecond, 100 * time.Millisecond, true},
+		{"only the beep left", 0, 500 * time.Millisecond, 0, false},
+		{"interval without ticks", 1, 10 * time.Second, 0, false},
+		{"missing interval", 2, 10 * time.Second, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, ok := m.Next(tt.segment, tt.remaining)
+			if next != tt.expected || ok != tt.ok {
+				t.Errorf("Next(%d, %v) = %v, %v, want %v, %v", tt.segment, tt.remaining, next, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+// TestMetronomeCheck tests when ticks are reached and accented
+func TestMetronomeCheck(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	m := NewMetronome([]time.Duration{time.Second})
+
+	steps := []struct {
+		name      string
+		segment   int
+		remaining time.Duration
+		at        time.Duration // since start
+		tick      bool
+		accent    bool
+	}{
+		{"first check", 0, 10 * time.Second, 0, false, false},
+		{"before a tick", 0, 9500 * time.Millisecond, 500 * time.Millisecond, false, false},
+		{"reaching a tick", 0, 9 * time.Second, time.Second, true, false},
+		{"just past a tick", 0, 7999 * time.Millisecond, 2001 * time.Millisecond, true, false},
+		{"accented", 0, 5 * time.Second, 5 * time.Second, true, true},
+		{"skipped ahead", 0, 2 * time.Second, 5500 * time.Millisecond, false, false},
+		{"last tick", 0, 1 * time.Second, 6500 * time.Millisecond, true, true},
+		{"the beep doesn't tick", 0, -time.Millisecond, 7501 * time.Millisecond, false, false},
+		{"next interval", 1, 10 * time.Second, 7502 * time.Millisecond, false, false},
+		{"first tick of the next interval", 1, 9 * time.Second, 8502 * time.Millisecond, true, false},
+	}
+
+	for _, step := range steps {
+		accent, ok := m.Check(step.segment, step.remaining, start.Add(step.at))
+		if ok != step.tick || accent != step.accent {
+			t.Errorf("%s: Check() = %v, %v, want %v, %v", step.name, accent, ok, step.accent, step.tick)
+		}
+	}
+}
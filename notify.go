@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	notificationsName      = "org.freedesktop.Notifications"
+	notificationsPath      = "/org/freedesktop/Notifications"
+	notificationsInterface = "org.freedesktop.Notifications"
+
+	// notifyTimeout limits a single Notify call on the bus
+	notifyTimeout = 2 * time.Second
+	// notifyQueueSize bounds the notifications waiting to be sent
+	notifyQueueSize = 8
+
+	defaultNotifyTitle = `{{if .Stopwatch}}Beep #{{.BeepCount}}{{else if .Label}}{{.Label}}{{else}}Interval {{.Segment}}/{{.Segments}}{{end}}`
+	defaultNotifyBody  = `{{if .Stopwatch}}{{clock (secs .Elapsed)}} elapsed{{else}}Next beep in {{duration (secs .Remaining)}}{{end}}`
+
+	// finishNotifyTitle is the title of the notification when the timer
+	// finishes, its body is the session summary
+	finishNotifyTitle = "Done"
+)
+
+// Notification actions offered on each notification. The keys are sent back
+// by the notification server when a button is clicked.
+const (
+	ActionPause = "pause"
+	ActionSkip  = "skip"
+)
+
+// notifyUrgencies maps -notify-urgency names to the urgency hint values of the
+// desktop notifications specification
+var notifyUrgencies = map[string]byte{
+	"low":      0,
+	"normal":   1,
+	"critical": 2,
+}
+
+// parseUrgencies parses a comma-separated list of urgencies, one per interval
+func parseUrgencies(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var urgencies []byte
+	for _, part := range strings.Split(s, ",") {
+		urgency, ok := notifyUrgencies[strings.TrimSpace(part)]
+		if !ok {
+			return nil, fmt.Errorf("invalid urgency: %s (want low, normal or critical)", part)
+		}
+		urgencies = append(urgencies, urgency)
+	}
+	return urgencies, nil
+}
+
+// Notifier shows a desktop notification on beeps and when the timer
+// finishes, talking to the org.freedesktop.Notifications service on the
+// session bus. In interval mode a beep starts the next interval, so the
+// notification announces that interval. Each notification replaces the
+// previous one and, while the timer runs, offers Pause and Skip buttons,
+// reported through Actions.
+type Notifier struct {
+	Title     *template.Template
+	Body      *template.Template
+	Urgencies []byte // per interval, normal when missing
+	OnError   func(err error)
+
+	conn    *dbus.Conn
+	obj     dbus.BusObject
+	lastID  uint32
+	started bool
+	queue   chan Event
+	actions chan string
+	done    chan struct{}
+}
+
+// NewNotifier creates a notifier on the given bus connection and starts
+// listening for action button clicks
+func NewNotifier(conn *dbus.Conn, title, body *template.Template, urgencies []byte) (*Notifier, error) {
+	n := &Notifier{
+		Title:     title,
+		Body:      body,
+		Urgencies: urgencies,
+		OnError: func(err error) {
+			fmt.Fprintf(os.Stderr, "Error sending notification: %v\n", err)
+		},
+		conn:    conn,
+		obj:     conn.Object(notificationsName, notificationsPath),
+		queue:   make(chan Event, notifyQueueSize),
+		actions: make(chan string, 1),
+		done:    make(chan struct{}),
+	}
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(notificationsPath),
+		dbus.WithMatchInterface(notificationsInterface),
+		dbus.WithMatchMember("ActionInvoked"),
+	); err != nil {
+		return nil, fmt.Errorf("subscribing to notification actions: %w", err)
+	}
+	signals := make(chan *dbus.Signal, 8)
+	conn.Signal(signals)
+
+	go n.worker(signals)
+	return n, nil
+}
+
+// parseNotifyTemplate parses a -notify-title or -notify-body template
+func parseNotifyTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Funcs(webhookFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, Event{Type: EventSegmentStart, Segment: 1, Segments: 1}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// Actions returns the keys of clicked action buttons
+func (n *Notifier) Actions() <-chan string {
+	return n.actions
+}
+
+// Send queues a notification if the event starts a new interval, is a beep
+// of the stopwatch or finishes the timer. The first interval is not
+// announced, since the user just started the timer. Interval beeps are
+// announced by the interval start that follows them.
+func (n *Notifier) Send(event Event) {
+	switch event.Type {
+	case EventSegmentStart:
+		if !n.started {
+			n.started = true
+			return
+		}
+	case EventBeep:
+		if !event.Stopwatch {
+			return
+		}
+	case EventFinish:
+	default:
+		return
+	}
+	select {
+	case n.queue <- event:
+	default:
+		n.OnError(fmt.Errorf("queue full, dropping notification"))
+	}
+}
+
+// Close stops the notifier after the queued notifications have been sent
+func (n *Notifier) Close() {
+	close(n.queue)
+	<-n.done
+}
+
+func (n *Notifier) worker(signals chan *dbus.Signal) {
+	for {
+		select {
+		case event, ok := <-n.queue:
+			if !ok {
+				n.conn.RemoveSignal(signals)
+				close(n.done)
+				return
+			}
+			if err := n.notify(event); err != nil {
+				n.OnError(err)
+			}
+		case signal := <-signals:
+			n.handleSignal(signal)
+		}
+	}
+}
+
+// notify sends the notification for the event, replacing the previous one
+func (n *Notifier) notify(event Event) error {
+	title, body, actions := finishNotifyTitle, "", []string{}
+	if event.Summary != nil {
+		body = event.Summary.String()
+	}
+	if event.Type != EventFinish {
+		var err error
+		if title, err = renderEventTemplate(n.Title, event); err != nil {
+			return err
+		}
+		if body, err = renderEventTemplate(n.Body, event); err != nil {
+			return err
+		}
+		actions = []string{ActionPause, "Pause", ActionSkip, "Skip"}
+	}
+
+	urgency := notifyUrgencies["normal"]
+	if i := event.Segment - 1; i >= 0 && i < len(n.Urgencies) && event.Type != EventFinish {
+		urgency = n.Urgencies[i]
+	}
+	hints := map[string]dbus.Variant{
+		"urgency": dbus.MakeVariant(urgency),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	call := n.obj.CallWithContext(ctx, notificationsInterface+".Notify", 0,
+		"bleep", n.lastID, "", title, body, actions, hints, int32(-1))
+	if call.Err != nil {
+		return call.Err
+	}
+	return call.Store(&n.lastID)
+}
+
+// handleSignal forwards clicks on the buttons of our latest notification
+func (n *Notifier) handleSignal(signal *dbus.Signal) {
+	if signal.Name != notificationsInterface+".ActionInvoked" || len(signal.Body) != 2 {
+		return
+	}
+	id, ok := signal.Body[0].(uint32)
+	if !ok || id == 0 || id != n.lastID {
+		return
+	}
+	action, ok := signal.Body[1].(string)
+	if !ok || (action != ActionPause && action != ActionSkip) {
+		return
+	}
+	select {
+	case n.actions <- action:
+	default:
+	}
+}
+
+// renderEventTemplate executes a template on an event
+func renderEventTemplate(tmpl *template.Template, event Event) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// testBusConfig configures a private session bus for the notification tests
+const testBusConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:dir=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>`
+
+// startTestBus starts a private dbus-daemon and returns its address
+func startTestBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not available")
+	}
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "bus.conf")
+	config := strings.Replace(testBusConfig, "%s", dir, 1)
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+configPath, "--print-address", "--nofork")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading bus address: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+// fakeNotification is a Notify call received by the fake server
+type fakeNotification struct {
+	ReplacesID uint32
+	Summary    string
+	Body       string
+	Actions    []string
+	Urgency    byte
+}
+
+// fakeNotificationServer implements the parts of org.freedesktop.Notifications
+// used by the Notifier
+type fakeNotificationServer struct {
+	mu       sync.Mutex
+	nextID   uint32
+	received chan fakeNotification
+}
+
+func (s *fakeNotificationServer) Notify(appName string, replacesID uint32, icon, summary, body string,
+	actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := replacesID
+	if id == 0 {
+		s.nextID++
+		id = s.nextID
+	}
+	urgency, _ := hints["urgency"].Value().(byte)
+	s.received <- fakeNotification{
+		ReplacesID: replacesID,
+		Summary:    summary,
+		Body:       body,
+		Actions:    actions,
+		Urgency:    urgency,
+	}
+	return id, nil
+}
+
+// connectTestBus connects to the private bus and fails the test on error
+func connectTestBus(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("connecting to test bus: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// waitNotification waits for the fake server to receive a notification
+func waitNotification(t *testing.T, server *fakeNotificationServer) fakeNotification {
+	t.Helper()
+	select {
+	case n := <-server.received:
+		return n
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for notification")
+		return fakeNotification{}
+	}
+}
+
+// TestNotifier tests notifications against a private session bus
+func TestNotifier(t *testing.T) {
+	address := startTestBus(t)
+
+	serverConn := connectTestBus(t, address)
+	server := &fakeNotificationServer{received: make(chan fakeNotification, 4)}
+	if err := serverConn.Export(server, notificationsPath, notificationsInterface); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := serverConn.RequestName(notificationsName, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("RequestName: reply %v, err %v", reply, err)
+	}
+
+	title := template.Must(parseNotifyTemplate("title", defaultNotifyTitle))
+	body := template.Must(parseNotifyTemplate("body", defaultNotifyBody))
+	notifier, err := NewNotifier(connectTestBus(t, address), title, body, []byte{2, 0})
+	if err != nil {
+		t.Fatalf("NewNotifier error: %v", err)
+	}
+	notifier.OnError = func(err error) {
+		t.Errorf("unexpected notifier error: %v", err)
+	}
+
+	// The first interval is not announced
+	notifier.Send(Event{Type: EventSegmentStart, Segment: 1, Segments: 2, Label: "work", Remaining: 1500})
+	// Interval beeps are announced by the interval start that follows
+	notifier.Send(Event{Type: EventBeep, Segment: 1, Segments: 2})
+	notifier.Send(Event{Type: EventPause, Segment: 1, Segments: 2})
+	notifier.Send(Event{Type: EventSegmentStart, Segment: 2, Segments: 2, Label: "break", Remaining: 300})
+
+	first := waitNotification(t, server)
+	if first.Summary != "break" || first.Body != "Next beep in 5m 0s" {
+		t.Errorf("first notification = %q / %q", first.Summary, first.Body)
+	}
+	if first.ReplacesID != 0 {
+		t.Errorf("first ReplacesID = %d, want 0", first.ReplacesID)
+	}
+	if first.Urgency != 0 {
+		t.Errorf("Urgency = %d, want 0 (low for interval 2)", first.Urgency)
+	}
+	if strings.Join(first.Actions, ",") != "pause,Pause,skip,Skip" {
+		t.Errorf("Actions = %v", first.Actions)
+	}
+
+	notifier.Send(Event{Type: EventSegmentStart, Segment: 1, Segments: 2, Remaining: 1500})
+	second := waitNotification(t, server)
+	if second.ReplacesID != 1 {
+		t.Errorf("second ReplacesID = %d, want 1 (replace instead of stacking)", second.ReplacesID)
+	}
+	if second.Summary != "Interval 1/2" {
+		t.Errorf("second Summary = %q, want %q", second.Summary, "Interval 1/2")
+	}
+	if second.Urgency != 2 {
+		t.Errorf("Urgency = %d, want 2 (critical for interval 1)", second.Urgency)
+	}
+
+	// Stopwatch beeps start no interval and are announced themselves
+	notifier.Send(Event{Type: EventBeep, Segment: 1, Segments: 1, Stopwatch: true, BeepCount: 3, Elapsed: 905})
+	third := waitNotification(t, server)
+	if third.Summary != "Beep #3" || third.Body != "15:05 elapsed" {
+		t.Errorf("stopwatch notification = %q / %q", third.Summary, third.Body)
+	}
+
+	// Clicks on our notification are forwarded, others are ignored
+	serverConn.Emit(notificationsPath, notificationsInterface+".ActionInvoked", uint32(42), ActionPause)
+	serverConn.Emit(notificationsPath, notificationsInterface+".ActionInvoked", uint32(1), ActionSkip)
+	select {
+	case action := <-notifier.Actions():
+		if action != ActionSkip {
+			t.Errorf("action = %q, want %q", action, ActionSkip)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for action")
+	}
+
+	// Finishing replaces the notification with the summary, without buttons
+	summary := Summary{Reason: ReasonFinished, Elapsed: 1800, Beeps: 2, Completed: 2}
+	notifier.Send(Event{Type: EventFinish, Segment: 1, Segments: 2, Summary: &summary})
+	done := waitNotification(t, server)
+	if done.Summary != "Done" || done.Body != "Finished after 30m 0s: 2 beeps, 2 intervals completed" {
+		t.Errorf("finish notification = %q / %q", done.Summary, done.Body)
+	}
+	if done.ReplacesID != 1 || len(done.Actions) != 0 || done.Urgency != 1 {
+		t.Errorf("finish notification replaces %d, actions %v, urgency %d; want 1, none, 1",
+			done.ReplacesID, done.Actions, done.Urgency)
+	}
+
+	notifier.Close()
+}
+
+// TestParseUrgencies tests the parseUrgencies function
+func TestParseUrgencies(t *testing.T) {
+	urgencies, err := parseUrgencies("low, normal,critical")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(urgencies) != string([]byte{0, 1, 2}) {
+		t.Errorf("parseUrgencies() = %v, want [0 1 2]", urgencies)
+	}
+
+	if urgencies, err := parseUrgencies(""); err != nil || urgencies != nil {
+		t.Errorf("parseUrgencies(\"\") = %v, %v", urgencies, err)
+	}
+	if _, err := parseUrgencies("urgent"); err == nil {
+		t.Error("expected error for unknown urgency")
+	}
+}
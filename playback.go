@@ -0,0 +1,204 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Playback policies for a sound that starts while others are playing
+const (
+	PolicyMix   = "mix"   // play it over them
+	PolicyQueue = "queue" // play it after them
+	PolicyCut   = "cut"   // stop them
+)
+
+// defaultMaxSounds is how many sounds play or wait at once unless
+// -max-sounds says otherwise
+const defaultMaxSounds = 4
+
+// audioPlayer is the part of *oto.Player that Playback uses
+type audioPlayer interface {
+	Play()
+	Pause()
+	SetVolume(volume float64)
+	BufferedSize() int
+	Err() error
+	Close() error
+}
+
+// Playback plays sounds on the audio context, deciding by its policy what
+// happens to sounds already playing. It keeps at most MaxSounds players:
+// mixing stops the oldest sound to make room, queueing drops sounds that
+// find the queue full.
+type Playback struct {
+	Policy    string
+	MaxSounds int
+
+	newPlayer      func(io.Reader) audioPlayer
+	bytesPerSecond func() int
+
+	mu      sync.Mutex
+	playing []*playingSound
+	queue   []*playingSound
+	pending sync.WaitGroup // sounds playing or queued
+}
+
+// playingSound is a sound handed to Playback
+type playingSound struct {
+	r       io.Reader
+	volume  float64
+	player  audioPlayer
+	ended   chan struct{} // closed once the player has read the whole sound
+	stopped chan struct{} // closed when the sound is finished or cut off
+	done    bool
+}
+
+// NewPlayback creates a playback manager playing on the audio context
+func NewPlayback(policy string, maxSounds int) *Playback {
+	return &Playback{
+		Policy:    policy,
+		MaxSounds: maxSounds,
+		newPlayer: func(r io.Reader) audioPlayer {
+			return audioContext.NewPlayer(r)
+		},
+		bytesPerSecond: func() int { return audioSampleRate * 4 },
+	}
+}
+
+// Play plays 16-bit stereo PCM at the given volume without blocking
+func (p *Playback) Play(r io.Reader, volume float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := &playingSound{r: r, volume: volume, ended: make(chan struct{}), stopped: make(chan struct{})}
+	switch p.Policy {
+	case PolicyCut:
+		for _, other := range slices.Concat(p.queue, p.playing) {
+			p.stop(other)
+		}
+		p.queue = nil
+	case PolicyQueue:
+		if len(p.playing) > 0 {
+			if len(p.playing)+len(p.queue) >= max(p.MaxSounds, 1) {
+				return
+			}
+			p.pending.Add(1)
+			p.queue = append(p.queue, s)
+			return
+		}
+	default:
+		for len(p.playing) >= max(p.MaxSounds, 1) {
+			p.stop(p.playing[0])
+		}
+	}
+	p.pending.Add(1)
+	p.start(s)
+}
+
+// start plays a sound; p.mu is held
+func (p *Playback) start(s *playingSound) {
+	s.player = p.newPlayer(&endReader{r: s.r, end: func() { close(s.ended) }})
+	s.player.SetVolume(s.volume)
+	s.player.Play()
+	p.playing = append(p.playing, s)
+	go p.drain(s)
+}
+
+// drain waits until the player has read the end of the sound and played
+// what it buffered, then finishes the sound. The player reads the sound
+// under its own lock, so it is asked how much it buffered from here rather
+// than from its reader.
+func (p *Playback) drain(s *playingSound) {
+	select {
+	case <-s.ended:
+	case <-s.stopped:
+		return
+	}
+	buffered := time.NewTimer(time.Duration(s.player.BufferedSize()) * time.Second / time.Duration(p.bytesPerSecond()))
+	defer buffered.Stop()
+	select {
+	case <-buffered.C:
+	case <-s.stopped:
+		return
+	}
+	if err := s.player.Err(); err != nil {
+		onAudioError(err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.finish(s)
+}
+
+// stop cuts a sound off; p.mu is held
+func (p *Playback) stop(s *playingSound) {
+	if s.player != nil {
+		s.player.Pause()
+	}
+	p.finish(s)
+}
+
+// finish releases the player of a sound that ended or was stopped and
+// starts the next queued one; p.mu is held
+func (p *Playback) finish(s *playingSound) {
+	if s.done {
+		return
+	}
+	s.done = true
+	close(s.stopped)
+	if s.player != nil {
+		s.player.Close()
+	}
+	p.playing = removeSound(p.playing, s)
+	p.queue = removeSound(p.queue, s)
+	p.pending.Done()
+	if len(p.playing) == 0 && len(p.queue) > 0 {
+		next := p.queue[0]
+		p.queue = p.queue[1:]
+		p.start(next)
+	}
+}
+
+// removeSound returns sounds without s
+func removeSound(sounds []*playingSound, s *playingSound) []*playingSound {
+	return slices.DeleteFunc(sounds, func(other *playingSound) bool { return other == s })
+}
+
+// Wait blocks until all sounds, including queued ones, have finished or
+// the timeout passes. It reports whether they finished.
+func (p *Playback) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		p.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Playing returns the number of sounds playing and queued
+func (p *Playback) Playing() (playing, queued int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.playing), len(p.queue)
+}
+
+// endReader calls end once its reader is used up
+type endReader struct {
+	r    io.Reader
+	once sync.Once
+	end  func()
+}
+
+func (e *endReader) Read(b []byte) (int, error) {
+	n, err := e.r.Read(b)
+	if err != nil {
+		e.once.Do(e.end)
+	}
+	return n, err
+}
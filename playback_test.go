@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePlayer stands in for an audio player; end plays its sound to the end
+type fakePlayer struct {
+	mu       sync.Mutex
+	r        io.Reader
+	volume   float64
+	playing  bool
+	closed   bool
+	buffered int // bytes still to play after the end of the sound was read
+}
+
+func (f *fakePlayer) Play()                    { f.set(func() { f.playing = true }) }
+func (f *fakePlayer) Pause()                   { f.set(func() { f.playing = false }) }
+func (f *fakePlayer) SetVolume(volume float64) { f.set(func() { f.volume = volume }) }
+func (f *fakePlayer) BufferedSize() int        { return f.buffered }
+func (f *fakePlayer) Err() error               { return nil }
+func (f *fakePlayer) Close() error             { f.set(func() { f.closed = true }); return nil }
+
+func (f *fakePlayer) set(change func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	change()
+}
+
+func (f *fakePlayer) end() {
+	f.Pause()
+	io.ReadAll(f.r)
+}
+
+// state returns whether the player is playing and closed
+func (f *fakePlayer) state() (playing, closed bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.playing, f.closed
+}
+
+// newTestPlayback returns a Playback creating fake players, and a function
+// returning the players created so far
+func newTestPlayback(policy string, maxSounds int) (*Playback, func() []*fakePlayer) {
+	var mu sync.Mutex
+	var players []*fakePlayer
+	p := NewPlayback(policy, maxSounds)
+	p.newPlayer = func(r io.Reader) audioPlayer {
+		mu.Lock()
+		defer mu.Unlock()
+		player := &fakePlayer{r: r}
+		players = append(players, player)
+		return player
+	}
+	p.bytesPerSecond = func() int { return 4000 }
+	return p, func() []*fakePlayer {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*fakePlayer(nil), players...)
+	}
+}
+
+// waitPlaying waits until the playback has the given number of sounds
+// playing and queued
+func waitPlaying(t *testing.T, p *Playback, playing, queued int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		gotPlaying, gotQueued := p.Playing()
+		if gotPlaying == playing && gotQueued == queued {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Playing() = %d, %d, want %d, %d", gotPlaying, gotQueued, playing, queued)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestPlayback tests the overlap policies and the limit on sounds
+func TestPlayback(t *testing.T) {
+	sound := func() io.Reader { return bytes.NewReader(make([]byte, 16)) }
+
+	t.Run("mix stops the oldest sound at the limit", func(t *testing.T) {
+		p, players := newTestPlayback(PolicyMix, 2)
+		p.Play(sound(), 0.5)
+		p.Play(sound(), 1)
+		p.Play(sound(), 1)
+		waitPlaying(t, p, 2, 0)
+
+		all := players()
+		if playing, closed := all[0].state(); playing || !closed {
+			t.Errorf("oldest sound playing = %v, closed = %v, want stopped", playing, closed)
+		}
+		if all[0].volume != 0.5 {
+			t.Errorf("volume = %v, want 0.5", all[0].volume)
+		}
+		for _, player := range all[1:] {
+			if playing, _ := player.state(); !playing {
+				t.Error("newer sound isn't playing")
+			}
+			player.end()
+		}
+		if !p.Wait(time.Second) {
+			t.Fatal("Wait() timed out")
+		}
+		waitPlaying(t, p, 0, 0)
+	})
+
+	t.Run("queue plays one sound after the other", func(t *testing.T) {
+		p, players := newTestPlayback(PolicyQueue, 2)
+		p.Play(sound(), 1)
+		p.Play(sound(), 1)
+		p.Play(sound(), 1) // the queue is full
+		waitPlaying(t, p, 1, 1)
+		if n := len(players()); n != 1 {
+			t.Fatalf("%d players before the first sound ended, want 1", n)
+		}
+
+		players()[0].end()
+		waitPlaying(t, p, 1, 0)
+		players()[1].end()
+		if !p.Wait(time.Second) {
+			t.Fatal("Wait() timed out")
+		}
+		if n := len(players()); n != 2 {
+			t.Errorf("%d sounds played, want 2", n)
+		}
+	})
+
+	t.Run("cut stops the sound playing", func(t *testing.T) {
+		p, players := newTestPlayback(PolicyCut, 2)
+		p.Play(sound(), 1)
+		p.Play(sound(), 1)
+		waitPlaying(t, p, 1, 0)
+		if playing, closed := players()[0].state(); playing || !closed {
+			t.Errorf("first sound playing = %v, closed = %v, want stopped", playing, closed)
+		}
+	})
+
+	t.Run("a sound ends after the player's buffer", func(t *testing.T) {
+		p, players := newTestPlayback(PolicyMix, 2)
+		p.Play(sound(), 1)
+		player := players()[0]
+		player.buffered = 400 // 100ms at 4000 bytes per second
+		player.end()
+		if p.Wait(20 * time.Millisecond) {
+			t.Error("Wait() = true with buffered sound left to play")
+		}
+		if !p.Wait(time.Second) {
+			t.Fatal("Wait() timed out")
+		}
+		if _, closed := player.state(); !closed {
+			t.Error("player not closed after the sound ended")
+		}
+	})
+
+	t.Run("wait times out", func(t *testing.T) {
+		p, _ := newTestPlayback(PolicyMix, 2)
+		p.Play(sound(), 1)
+		if p.Wait(10 * time.Millisecond) {
+			t.Error("Wait() = true with a sound playing")
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule decides when beeps are due for timers that follow the wall clock
+// rather than fixed intervals
+type Schedule interface {
+	// Next returns the first beep time after t, or the zero time if the
+	// schedule has no more beeps
+	Next(t time.Time) time.Time
+}
+
+// untilLayouts are the accepted -until formats besides a plain time of day
+var untilLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// clockLayouts are the accepted time of day formats
+var clockLayouts = []string{"15:04", "15:04:05"}
+
+// UntilSchedule beeps at a wall-clock time, and with Daily set at the same
+// time on every following day. Days are counted in the schedule's location,
+// so a daily 09:00 stays at 09:00 across daylight saving changes.
+type UntilSchedule struct {
+	Target   time.Time // first beep
+	Daily    bool
+	Location *time.Location
+	Hour     int
+	Minute   int
+	Second   int
+}
+
+// ParseUntil parses an -until value: a time of day ("17:30"), which means
+// its next occurrence after now, or a date and time ("2026-10-20T09:00").
+// Times without a UTC offset are read in loc.
+func ParseUntil(s string, now time.Time, loc *time.Location, daily bool) (*UntilSchedule, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range clockLayouts {
+		clock, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		u := &UntilSchedule{
+			Daily:    daily,
+			Location: loc,
+			Hour:     clock.Hour(),
+			Minute:   clock.Minute(),
+			Second:   clock.Second(),
+		}
+		u.Target = u.nextWallClock(now)
+		return u, nil
+	}
+
+	for _, layout := range untilLayouts {
+		target, err := time.ParseInLocation(layout, s, loc)
+		if err != nil {
+			continue
+		}
+		if !target.After(now) {
+			return nil, fmt.Errorf("-until %s is in the past", s)
+		}
+		local := target.In(loc)
+		return &UntilSchedule{
+			Target:   target,
+			Daily:    daily,
+			Location: loc,
+			Hour:     local.Hour(),
+			Minute:   local.Minute(),
+			Second:   local.Second(),
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid -until value %q (want HH:MM, HH:MM:SS or YYYY-MM-DDTHH:MM)", s)
+}
+
+// Next returns the target, then with Daily the same wall-clock time on the
+// following days
+func (u *UntilSchedule) Next(t time.Time) time.Time {
+	if t.Before(u.Target) {
+		return u.Target
+	}
+	if !u.Daily {
+		return time.Time{}
+	}
+	return u.nextWallClock(t)
+}
+
+// nextWallClock returns the first time after t at which the clocks in the
+// schedule's location show its time of day
+func (u *UntilSchedule) nextWallClock(t time.Time) time.Time {
+	local := t.In(u.Location)
+	for day := 0; day <= 2; day++ {
+		candidate := wallClock(local.Year(), local.Month(), local.Day()+day, u.Hour, u.Minute, u.Second, u.Location)
+		if candidate.After(t) {
+			return candidate
+		}
+	}
+	// Unreachable: every day has the time of day or the time after a gap
+	return time.Time{}
+}
+
+// wallClock returns the instant at which the clocks in loc show the given
+// date and time. When the clocks are set back and the time occurs twice, the
+// earlier instant is used; when they skip ahead over it, the first instant
+// after the gap is used, so a beep is never lost or doubled.
+func wallClock(year int, month time.Month, day, hour, min, sec int, loc *time.Location) time.Time {
+	naive := time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+
+	// Try each UTC offset in effect around that day
+	var earliest time.Time
+	for _, probe := range []time.Duration{-36 * time.Hour, -12 * time.Hour, 0, 12 * time.Hour, 36 * time.Hour} {
+		_, offset := naive.Add(probe).In(loc).Zone()
+		candidate := naive.Add(-time.Duration(offset) * time.Second).In(loc)
+		y, m, d := candidate.Date()
+		if candidate.Hour() != hour || candidate.Minute() != min || candidate.Second() != sec ||
+			y != naive.Year() || m != naive.Month() || d != naive.Day() {
+			continue
+		}
+		if earliest.IsZero() || candidate.Before(earliest) {
+			earliest = candidate
+		}
+	}
+	if earliest.IsZero() {
+		// The time falls into a gap, time.Date moves it forward by the gap
+		return time.Date(year, month, day, hour, min, sec, 0, loc)
+	}
+	return earliest
+}
+
+// Describe returns a short description for tooltips and the verbose banner
+func (u *UntilSchedule) Describe() string {
+	clock := fmt.Sprintf("%02d:%02d", u.Hour, u.Minute)
+	if u.Second != 0 {
+		clock += fmt.Sprintf(":%02d", u.Second)
+	}
+	if u.Daily {
+		return fmt.Sprintf("Beeping daily at %s", clock)
+	}
+	return fmt.Sprintf("Counting down to %s", u.Target.In(u.Location).Format("Mon Jan 2 15:04"))
+}
+
+// alignIntervals returns the interval and its end at t when the intervals
+// repeat back to back from midnight, so every timer with the same intervals
+// beeps at the same moments. With -m 15 that is :00, :15, :30 and :45.
+func alignIntervals(intervals []time.Duration, t time.Time) (int, time.Time) {
+	var cycle time.Duration
+	for _, interval := range intervals {
+		cycle += interval
+	}
+	// Count wall-clock time, so the boundaries stay put across daylight
+	// saving changes
+	hour, min, sec := t.Clock()
+	sinceMidnight := time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute +
+		time.Duration(sec)*time.Second + time.Duration(t.Nanosecond())
+	pos := sinceMidnight % cycle
+
+	for i, interval := range intervals {
+		if pos < interval {
+			return i, t.Add(interval - pos)
+		}
+		pos -= interval
+	}
+	// Unreachable: pos is less than the sum of the intervals
+	return 0, t.Add(intervals[0])
+}
+
+// alignFlag is a flag.Value for -align: given on its own it aligns the
+// intervals to the clock, given a value (-align='*/20 9-17 * * 1-5') it
+// holds a cron expression
+type alignFlag struct {
+	Enabled bool
+	Cron    string
+}
+
+func (a *alignFlag) String() string {
+	return a.Cron
+}
+
+func (a *alignFlag) Set(value string) error {
+	switch value {
+	case "true":
+		a.Enabled, a.Cron = true, ""
+	case "false":
+		a.Enabled, a.Cron = false, ""
+	default:
+		a.Enabled, a.Cron = true, value
+	}
+	return nil
+}
+
+// IsBoolFlag lets -align be given without a value
+func (a *alignFlag) IsBoolFlag() bool {
+	return true
+}
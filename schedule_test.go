@@ -0,0 +1,267 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+)
+
+// loadLocation loads a time zone or skips the test if tzdata is missing
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+// TestParseUntil tests the ParseUntil function
+func TestParseUntil(t *testing.T) {
+	loc := loadLocation(t, "Europe/Berlin")
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, loc)
+
+	tests := []struct {
+		name        string
+		value       string
+		expected    time.Time
+		expectError bool
+	}{
+		{"later today", "17:30", time.Date(2026, 10, 18, 17, 30, 0, 0, loc), false},
+		{"with seconds", "17:30:15", time.Date(2026, 10, 18, 17, 30, 15, 0, loc), false},
+		{"tomorrow", "09:00", time.Date(2026, 10, 19, 9, 0, 0, 0, loc), false},
+		{"date and time", "2026-10-20T09:00", time.Date(2026, 10, 20, 9, 0, 0, 0, loc), false},
+		{"date with space", "2026-10-20 09:00:30", time.Date(2026, 10, 20, 9, 0, 30, 0, loc), false},
+		{"utc offset", "2026-10-20T09:00:00Z", time.Date(2026, 10, 20, 11, 0, 0, 0, loc), false},
+		{"past", "2026-10-17T09:00", time.Time{}, true},
+		{"invalid", "tomorrow", time.Time{}, true},
+		{"invalid clock", "25:00", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := ParseUntil(tt.value, now, loc, false)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseUntil(%q) expected error, got %v", tt.value, u.Target)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseUntil(%q) unexpected error: %v", tt.value, err)
+			}
+			if !u.Target.Equal(tt.expected) {
+				t.Errorf("ParseUntil(%q) = %v, want %v", tt.value, u.Target, tt.expected)
+			}
+		})
+	}
+}
+
+// TestUntilScheduleNext tests daily re-arming across daylight saving changes
+func TestUntilScheduleNext(t *testing.T) {
+	loc := loadLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name     string
+		value    string
+		now      time.Time
+		daily    bool
+		expected []time.Time
+	}{
+		{
+			name:     "once",
+			value:    "17:30",
+			now:      time.Date(2026, 10, 18, 12, 0, 0, 0, loc),
+			expected: []time.Time{time.Date(2026, 10, 18, 17, 30, 0, 0, loc), {}},
+		},
+		{
+			name:  "daily keeps wall-clock time after clocks go back",
+			value: "09:00",
+			now:   time.Date(2026, 10, 24, 12, 0, 0, 0, loc),
+			daily: true,
+			expected: []time.Time{
+				time.Date(2026, 10, 25, 9, 0, 0, 0, loc),
+				time.Date(2026, 10, 26, 9, 0, 0, 0, loc),
+			},
+		},
+		{
+			name:  "time skipped by spring forward",
+			value: "02:30",
+			now:   time.Date(2026, 3, 28, 12, 0, 0, 0, loc),
+			daily: true,
+			expected: []time.Time{
+				time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC), // 03:30 CEST
+				time.Date(2026, 3, 30, 2, 30, 0, 0, loc),
+			},
+		},
+		{
+			name:  "repeated time beeps once",
+			value: "02:30",
+			now:   time.Date(2026, 10, 24, 12, 0, 0, 0, loc),
+			daily: true,
+			expected: []time.Time{
+				time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC), // first 02:30, CEST
+				time.Date(2026, 10, 26, 2, 30, 0, 0, loc),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := ParseUntil(tt.value, tt.now, loc, tt.daily)
+			if err != nil {
+				t.Fatal(err)
+			}
+			at := tt.now
+			for i, want := range tt.expected {
+				next := u.Next(at)
+				if !next.Equal(want) {
+					t.Fatalf("beep %d = %v, want %v", i+1, next, want)
+				}
+				at = next
+			}
+		})
+	}
+}
+
+// TestUntilScheduleDescribe tests the Describe method
+func TestUntilScheduleDescribe(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	once, _ := ParseUntil("17:30", now, time.UTC, false)
+	if got := once.Describe(); got != "Counting down to Sun Oct 18 17:30" {
+		t.Errorf("Describe() = %q", got)
+	}
+	daily, _ := ParseUntil("09:00:30", now, time.UTC, true)
+	if got := daily.Describe(); got != "Beeping daily at 09:00:30" {
+		t.Errorf("Describe() = %q", got)
+	}
+}
+
+// TestScheduledTimerState tests a timer state following a schedule
+func TestScheduledTimerState(t *testing.T) {
+	now := time.Now()
+	u := &UntilSchedule{Target: now.Add(time.Hour), Location: time.Local}
+
+	ts := NewScheduledTimerState(u, false)
+	if remaining := ts.Remaining(); remaining <= 59*time.Minute || remaining > time.Hour {
+		t.Errorf("Remaining() = %v, expected about 1h", remaining)
+	}
+
+	// Pausing doesn't move the target
+	ts.TogglePause()
+	ts.TogglePause()
+	if !ts.NextBeep.Equal(u.Target) {
+		t.Errorf("NextBeep = %v after pause, want %v", ts.NextBeep, u.Target)
+	}
+
+	ts.SetRemaining(time.Minute)
+	ts.ResetTimer()
+	if !ts.NextBeep.Equal(u.Target) {
+		t.Errorf("NextBeep = %v after reset, want %v", ts.NextBeep, u.Target)
+	}
+
+	ts.TriggerBeep()
+	if !ts.Done {
+		t.Error("one-off schedule should be done after its beep")
+	}
+
+	// A daily schedule re-arms for the next day
+	daily := &UntilSchedule{Target: now.Add(time.Minute), Daily: true, Location: time.UTC}
+	at := now.Add(time.Minute).UTC()
+	daily.Hour, daily.Minute, daily.Second = at.Hour(), at.Minute(), at.Second()
+	ts = NewScheduledTimerState(daily, false)
+	ts.TriggerBeep()
+	if ts.Done {
+		t.Fatal("daily schedule should not be done")
+	}
+	if remaining := ts.Remaining(); remaining <= 24*time.Hour || remaining > 24*time.Hour+time.Minute {
+		t.Errorf("Remaining() = %v after daily beep, expected about 1 day", remaining)
+	}
+}
+
+// TestAlignIntervals tests the alignIntervals function
+func TestAlignIntervals(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals []time.Duration
+		at        time.Time
+		index     int
+		next      time.Time
+	}{
+		{"quarter hours", []time.Duration{15 * time.Minute},
+			time.Date(2026, 10, 18, 11, 47, 30, 0, time.UTC), 0, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)},
+		{"on a boundary", []time.Duration{15 * time.Minute},
+			time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC), 0, time.Date(2026, 10, 18, 12, 15, 0, 0, time.UTC)},
+		{"pomodoro work", []time.Duration{25 * time.Minute, 5 * time.Minute},
+			time.Date(2026, 10, 18, 11, 40, 0, 0, time.UTC), 0, time.Date(2026, 10, 18, 11, 55, 0, 0, time.UTC)},
+		{"pomodoro break", []time.Duration{25 * time.Minute, 5 * time.Minute},
+			time.Date(2026, 10, 18, 11, 56, 0, 0, time.UTC), 1, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)},
+		{"seconds", []time.Duration{45 * time.Second},
+			time.Date(2026, 10, 18, 0, 1, 0, 0, time.UTC), 0, time.Date(2026, 10, 18, 0, 1, 30, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, next := alignIntervals(tt.intervals, tt.at)
+			if index != tt.index || !next.Equal(tt.next) {
+				t.Errorf("alignIntervals() = %d, %v, want %d, %v", index, next, tt.index, tt.next)
+			}
+		})
+	}
+}
+
+// TestAlignedTimerState tests that beeps and resets fall back onto the
+// clock boundaries
+func TestAlignedTimerState(t *testing.T) {
+	intervals := []time.Duration{time.Hour}
+	ts := NewTimerState(intervals, []int{60}, []int{0}, false)
+	ts.Aligned = true
+	ts.alignToClock()
+
+	// Compare wall-clock times, the monotonic readings differ by nanoseconds
+	boundary := ts.NextBeep.Round(0)
+	if boundary.Minute() != 0 || boundary.Second() != 0 || boundary.Nanosecond() != 0 {
+		t.Fatalf("NextBeep = %v, want a full hour", boundary)
+	}
+
+	ts.SetRemaining(time.Minute)
+	ts.ResetTimer()
+	if !ts.NextBeep.Round(0).Equal(boundary) {
+		t.Errorf("NextBeep = %v after reset, want %v", ts.NextBeep, boundary)
+	}
+
+	ts.Skip()
+	ts.TriggerBeep()
+	if !ts.NextBeep.Round(0).Equal(boundary) || ts.BeepCount != 1 {
+		t.Errorf("NextBeep = %v after beep, want %v", ts.NextBeep, boundary)
+	}
+}
+
+// TestAlignFlag tests -align with and without a cron expression
+func TestAlignFlag(t *testing.T) {
+	tests := []struct {
+		args    []string
+		enabled bool
+		cron    string
+	}{
+		{[]string{}, false, ""},
+		{[]string{"-align"}, true, ""},
+		{[]string{"-align=*/20 9-17 * * 1-5"}, true, "*/20 9-17 * * 1-5"},
+		{[]string{"-align=@hourly", "-align=false"}, false, ""},
+	}
+
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("bleep", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		var align alignFlag
+		fs.Var(&align, "align", "")
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatalf("Parse(%v): %v", tt.args, err)
+		}
+		if align.Enabled != tt.enabled || align.Cron != tt.cron {
+			t.Errorf("Parse(%v) = %+v, want enabled %v, cron %q", tt.args, align, tt.enabled, tt.cron)
+		}
+	}
+}
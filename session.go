@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Exit codes: a finished timer exits 0, one stopped by a signal exits
+// 128 plus the signal number like a shell would report it
+const (
+	exitOK    = 0
+	exitError = 1
+)
+
+// Reasons a timer stopped, reported in the session summary
+const (
+	ReasonFinished    = "finished"
+	ReasonInterrupted = "interrupted" // SIGINT, e.g. Ctrl+C
+	ReasonTerminated  = "terminated"  // SIGTERM, e.g. systemctl stop
+)
+
+// signalExitCode returns the exit code and stop reason for a signal
+func signalExitCode(sig os.Signal) (int, string) {
+	code := exitError
+	if s, ok := sig.(syscall.Signal); ok {
+		code = 128 + int(s)
+	}
+	if sig == syscall.SIGTERM {
+		return code, ReasonTerminated
+	}
+	return code, ReasonInterrupted
+}
+
+// Summary describes a session when the timer stops, attached to finish and
+// stop events. Times are in whole seconds.
+type Summary struct {
+	Reason    string `json:"reason"`
+	ExitCode  int    `json:"exit_code"`
+	Elapsed   int    `json:"elapsed"`
+	Paused    int    `json:"paused"`
+	Beeps     int    `json:"beeps"`
+	Completed int    `json:"completed"`
+	Skipped   int    `json:"skipped"`
+	Laps      int    `json:"laps,omitempty"`
+}
+
+// String returns the summary as one line, e.g. "Interrupted after 52m 10s:
+// 3 beeps, 2 intervals completed, 1 skipped"
+func (s Summary) String() string {
+	parts := []string{plural(s.Beeps, "beep"), plural(s.Completed, "interval") + " completed"}
+	if s.Skipped > 0 {
+		parts = append(parts, fmt.Sprintf("%d skipped", s.Skipped))
+	}
+	if s.Laps > 0 {
+		parts = append(parts, plural(s.Laps, "lap"))
+	}
+	if s.Paused > 0 {
+		parts = append(parts, "paused for "+formatDuration(secondsDuration(s.Paused)))
+	}
+	reason := s.Reason
+	if reason != "" {
+		reason = strings.ToUpper(reason[:1]) + reason[1:]
+	}
+	return fmt.Sprintf("%s after %s: %s", reason, formatDuration(secondsDuration(s.Elapsed)), strings.Join(parts, ", "))
+}
+
+// plural returns n and the noun, with an s unless n is 1
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// Session keeps the totals of a run from its events for the summary
+type Session struct {
+	started     time.Time
+	pausedSince time.Time // zero while running
+	paused      time.Duration
+	beeps       int
+	completed   int
+	skipped     int
+	laps        int
+}
+
+// Observe counts an event
+func (s *Session) Observe(event Event) {
+	switch event.Type {
+	case EventStart:
+		s.started = event.Timestamp
+		if event.Paused {
+			s.pausedSince = event.Timestamp
+		}
+	case EventBeep:
+		s.beeps++
+		// A manual beep restarts the interval rather than completing it
+		if event.BeepType != "manual" {
+			s.completed++
+		}
+	case EventSkip:
+		s.skipped++
+	case EventLap:
+		s.laps++
+	case EventPause:
+		s.pausedSince = event.Timestamp
+	case EventResume:
+		if !s.pausedSince.IsZero() {
+			s.paused += event.Timestamp.Sub(s.pausedSince)
+			s.pausedSince = time.Time{}
+		}
+	}
+}
+
+// Summary returns the totals of the session stopping at now
+func (s *Session) Summary(reason string, exitCode int, now time.Time) Summary {
+	paused := s.paused
+	if !s.pausedSince.IsZero() {
+		paused += now.Sub(s.pausedSince)
+	}
+	return Summary{
+		Reason:    reason,
+		ExitCode:  exitCode,
+		Elapsed:   int(now.Sub(s.started).Round(time.Second).Seconds()),
+		Paused:    int(paused.Round(time.Second).Seconds()),
+		Beeps:     s.beeps,
+		Completed: s.completed,
+		Skipped:   s.skipped,
+		Laps:      s.laps,
+	}
+}
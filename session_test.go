@@ -0,0 +1,126 @@
+package main
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestSession tests the totals of the session summary
+func TestSession(t *testing.T) {
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	at := func(seconds int, event Event) Event {
+		event.Timestamp = start.Add(time.Duration(seconds) * time.Second)
+		return event
+	}
+
+	tests := []struct {
+		name     string
+		events   []Event
+		stop     int // seconds after the start
+		expected Summary
+	}{
+		{
+			name: "beeps and skips",
+			events: []Event{
+				at(0, Event{Type: EventStart}),
+				at(60, Event{Type: EventBeep, BeepType: "automatic"}),
+				at(70, Event{Type: EventBeep, BeepType: "manual"}),
+				at(80, Event{Type: EventSkip}),
+				at(200, Event{Type: EventBeep, BeepType: "missed"}),
+			},
+			stop:     230,
+			expected: Summary{Elapsed: 230, Beeps: 3, Completed: 2, Skipped: 1},
+		},
+		{
+			name: "paused time",
+			events: []Event{
+				at(0, Event{Type: EventStart}),
+				at(10, Event{Type: EventPause, Paused: true}),
+				at(40, Event{Type: EventResume}),
+				at(50, Event{Type: EventPause, Paused: true}),
+			},
+			stop:     65,
+			expected: Summary{Elapsed: 65, Paused: 45},
+		},
+		{
+			name: "started paused",
+			events: []Event{
+				at(0, Event{Type: EventStart, Paused: true}),
+				at(20, Event{Type: EventResume}),
+			},
+			stop:     30,
+			expected: Summary{Elapsed: 30, Paused: 20},
+		},
+		{
+			name: "stopwatch laps",
+			events: []Event{
+				at(0, Event{Type: EventStart, Stopwatch: true}),
+				at(30, Event{Type: EventLap}),
+				at(45, Event{Type: EventLap}),
+			},
+			stop:     50,
+			expected: Summary{Elapsed: 50, Laps: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Session{}
+			for _, event := range tt.events {
+				s.Observe(event)
+			}
+			tt.expected.Reason, tt.expected.ExitCode = ReasonInterrupted, 130
+			got := s.Summary(ReasonInterrupted, 130, start.Add(time.Duration(tt.stop)*time.Second))
+			if got != tt.expected {
+				t.Errorf("Summary() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSummaryString tests the summary line printed when the timer stops
+func TestSummaryString(t *testing.T) {
+	tests := []struct {
+		summary  Summary
+		expected string
+	}{
+		{
+			Summary{Reason: ReasonFinished, Elapsed: 1500, Beeps: 1, Completed: 1},
+			"Finished after 25m 0s: 1 beep, 1 interval completed",
+		},
+		{
+			Summary{Reason: ReasonInterrupted, Elapsed: 3130, Paused: 300, Beeps: 3, Completed: 2, Skipped: 1},
+			"Interrupted after 52m 10s: 3 beeps, 2 intervals completed, 1 skipped, paused for 5m 0s",
+		},
+		{
+			Summary{Reason: ReasonTerminated, Elapsed: 95, Laps: 2},
+			"Terminated after 1m 35s: 0 beeps, 0 intervals completed, 2 laps",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.summary.String(); got != tt.expected {
+			t.Errorf("String() = %q, want %q", got, tt.expected)
+		}
+	}
+}
+
+// TestSignalExitCode tests the exit codes of stop signals
+func TestSignalExitCode(t *testing.T) {
+	tests := []struct {
+		signal syscall.Signal
+		code   int
+		reason string
+	}{
+		{syscall.SIGINT, 130, ReasonInterrupted},
+		{syscall.SIGTERM, 143, ReasonTerminated},
+	}
+
+	for _, tt := range tests {
+		code, reason := signalExitCode(tt.signal)
+		if code != tt.code || reason != tt.reason {
+			t.Errorf("signalExitCode(%v) = %d, %q, want %d, %q", tt.signal, code, reason, tt.code, tt.reason)
+		}
+	}
+}
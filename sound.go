@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ebitengine/oto/v3"
+)
+
+// audioSampleRate is the sample rate of the audio context, set by initAudio
+var audioSampleRate = defaultSampleRate
+
+// toneFade is the length of the fade in and out of generated tones, which
+// avoids clicks at the start and end
+const toneFade = 10 * time.Millisecond
+
+// generateTone returns a sine tone as 16-bit little-endian stereo PCM
+func generateTone(freq float64, duration time.Duration, volume float64, sampleRate int) []byte {
+	samples := int(duration.Seconds() * float64(sampleRate))
+	fade := int(toneFade.Seconds() * float64(sampleRate))
+	buf := make([]byte, samples*4)
+	for i := 0; i < samples; i++ {
+		gain := volume
+		if i < fade {
+			gain *= float64(i) / float64(fade)
+		} else if samples-i < fade {
+			gain *= float64(samples-i) / float64(fade)
+		}
+		v := int16(math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)) * gain * math.MaxInt16)
+		binary.LittleEndian.PutUint16(buf[i*4:], uint16(v))
+		binary.LittleEndian.PutUint16(buf[i*4+2:], uint16(v))
+	}
+	return buf
+}
+
+// playback plays all sounds but the cached ones, set up from -overlap and
+// -max-sounds
+var playback = NewPlayback(PolicyMix, defaultMaxSounds)
+
+// playPCM plays 16-bit stereo PCM at the given volume without blocking the
+// timer
+func playPCM(r io.Reader, volume float64) {
+	playback.Play(r, volume)
+}
+
+// cachedBufferLength is the player buffer of cached sounds, short so they
+// start right away
+const cachedBufferLength = 20 * time.Millisecond
+
+// CachedSound keeps generated PCM ready on its own player, so playing it
+// doesn't decode, allocate or start anything. Metronome ticks use it to
+// stay on time.
+type CachedSound struct {
+	generate func() []byte
+	once     sync.Once
+	player   *oto.Player
+}
+
+// NewCachedSound creates a sound generated when it is first prepared
+func NewCachedSound(generate func() []byte) *CachedSound {
+	return &CachedSound{generate: generate}
+}
+
+// Prepare generates the sound and creates its player, so the first play
+// isn't late
+func (c *CachedSound) Prepare() {
+	c.once.Do(func() {
+		c.player = audioContext.NewPlayer(bytes.NewReader(c.generate()))
+		c.player.SetBufferSize(int(cachedBufferLength.Seconds()*float64(audioSampleRate)) * 4)
+	})
+}
+
+// Play plays the sound from the start, cutting off the previous play if it
+// is still going on
+func (c *CachedSound) Play() {
+	c.Prepare()
+	if _, err := c.player.Seek(0, io.SeekStart); err != nil {
+		onAudioError(err)
+		return
+	}
+	c.player.Play()
+}
+
+// beepSound is the sound of the beep: the built-in MP3 unless -sound
+// replaces it. main decodes it before opening the audio context.
+var beepSound *Sound
+
+// beepPCM converts beepSound for the audio context once
+var beepPCM = sync.OnceValue(func() []byte {
+	return beepSound.PCM(audioSampleRate)
+})
+
+// playBeepVolume plays the beep sound at the given volume
+func playBeepVolume(volume float64) {
+	// Play the beep asynchronously so it doesn't block the timer
+	playPCM(bytes.NewReader(beepPCM()), volume)
+}
+
+const (
+	// minSampleRate is the lowest sample rate the audio context runs at;
+	// below it high notes of other sounds would be lost
+	minSampleRate = 44100
+	// maxSampleRate is the highest sample rate the audio context runs at
+	maxSampleRate = 96000
+	// defaultSampleRate is the rate of the audio context when the beep's
+	// is out of range, the usual rate of sound servers and sound cards
+	defaultSampleRate = 48000
+)
+
+// outputSampleRate returns the sample rate to open the audio context at
+// for a beep sound of the given rate. Sounds at other rates are resampled,
+// so the beep plays unchanged where it can.
+func outputSampleRate(beepRate int) int {
+	if beepRate < minSampleRate || beepRate > maxSampleRate {
+		return defaultSampleRate
+	}
+	return beepRate
+}
+
+// alarmFunc is the function called to play an -alarm beep at a volume. It
+// can be replaced in tests to prevent actual sound playback.
+var alarmFunc = playBeepVolume
+
+// playAlarm calls alarmFunc to play an alarm beep
+func playAlarm(volume float64) {
+	alarmFunc(volume)
+}
+
+// Warning sounds for -warn-sound
+const (
+	WarnSoundTone = "tone" // a short high tone, distinct from the beep
+	WarnSoundSoft = "soft" // the beep at a lower volume
+)
+
+const (
+	// warnToneFreq and warnToneLength shape the warning tone
+	warnToneFreq   = 1320.0
+	warnToneLength = 150 * time.Millisecond
+	// warnVolume is the volume of warnings relative to the beep
+	warnVolume = 0.4
+)
+
+// warnFunc is the function called to play a warning sound. It can be
+// replaced in tests to prevent actual sound playback.
+var warnFunc = playWarningImpl
+
+// warnSound is the -warn-sound setting
+var warnSound = WarnSoundTone
+
+// warnTone caches the generated warning tone
+var warnTone = sync.OnceValue(func() []byte {
+	return generateTone(warnToneFreq, warnToneLength, warnVolume, audioSampleRate)
+})
+
+// playWarning calls warnFunc to play a warning sound
+func playWarning() {
+	warnFunc()
+}
+
+// playWarningImpl plays the warning sound selected by warnSound
+func playWarningImpl() {
+	if warnSound == WarnSoundSoft {
+		playBeepVolume(warnVolume)
+		return
+	}
+	playPCM(bytes.NewReader(warnTone()), 1)
+}
+
+const (
+	// tickToneFreq and accentToneFreq are the pitches of metronome ticks,
+	// accentToneFreq for the last seconds before the beep
+	tickToneFreq   = 1000.0
+	accentToneFreq = 1760.0
+	tickToneLength = 40 * time.Millisecond
+	tickVolume     = 0.35
+	accentVolume   = 0.6
+)
+
+var (
+	tickSound = NewCachedSound(func() []byte {
+		return generateTone(tickToneFreq, tickToneLength, tickVolume, audioSampleRate)
+	})
+	accentSound = NewCachedSound(func() []byte {
+		return generateTone(accentToneFreq, tickToneLength, accentVolume, audioSampleRate)
+	})
+)
+
+// tickFunc is the function called to play a metronome tick. It can be
+// replaced in tests to prevent actual sound playback.
+var tickFunc = playTickImpl
+
+// playTick calls tickFunc to play a tick
+func playTick(accent bool) {
+	tickFunc(accent)
+}
+
+const (
+	// doneNoteLength is the length of each note of the done chime but the
+	// last, which rings out twice as long
+	doneNoteLength = 140 * time.Millisecond
+	doneVolume     = 0.6
+)
+
+// doneNotes are the pitches of the done chime, a rising major chord (C6,
+// E6, G6, C7) that can't be mistaken for the beep
+var doneNotes = []float64{1046.5, 1318.5, 1568.0, 2093.0}
+
+// doneChime caches the generated done chime
+var doneChime = sync.OnceValue(func() []byte {
+	var buf []byte
+	for i, freq := range doneNotes {
+		length := doneNoteLength
+		if i == len(doneNotes)-1 {
+			length *= 2
+		}
+		buf = append(buf, generateTone(freq, length, doneVolume, audioSampleRate)...)
+	}
+	return buf
+})
+
+// doneFunc is the function called to play the done sound at the end of a
+// -count or -for run. It can be replaced in tests to prevent actual sound
+// playback.
+var doneFunc = playDoneImpl
+
+// playDone calls doneFunc to play the done sound
+func playDone() {
+	doneFunc()
+}
+
+// playDoneImpl plays the done chime
+func playDoneImpl() {
+	playPCM(bytes.NewReader(doneChime()), 1)
+}
+
+// playTickImpl plays a tick, or an accented one
+func playTickImpl(accent bool) {
+	if accent {
+		accentSound.Play()
+		return
+	}
+	tickSound.Play()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// barColors maps output classes to the colors used by the status bar modes.
+// They match the palette in waybar-example/style.css.
+var barColors = map[string]string{
+	"counting": "#a6e3a1",
+	"paused":   "#f9e2af",
+	"beep":     "#f38ba8",
+	"warning":  "#fab387",
+	"alarm":    "#eba0ac",
+	"finished": "#89b4fa",
+}
+
+// statusFileMaxAge is how old the status file may get before -query treats
+// the writing instance as gone. A running instance rewrites it every second.
+const statusFileMaxAge = 3 * time.Second
+
+// formatBarOutput renders text for the polybar, i3blocks and tmux modes,
+// colored according to its class.
+func formatBarOutput(config OutputConfig, class, text string) string {
+	color := barColors[class]
+	switch config.Mode {
+	case ModePolybar:
+		// Formatting tags, wrapped in an action tag so a left click
+		// toggles pause the same way Waybar's on-click does
+		out := fmt.Sprintf("%%{F%s}%s%%{F-}", color, strings.ReplaceAll(text, "%", "%%"))
+		if config.PID > 0 {
+			out = fmt.Sprintf("%%{A1:kill -USR1 %d:}%s%%{A}", config.PID, out)
+		}
+		return out
+	case ModeI3blocks:
+		// Pango markup, requires markup=pango in the block config
+		weight := ""
+		if class == "beep" {
+			weight = ` weight="bold"`
+		}
+		return fmt.Sprintf(`<span foreground="%s"%s>%s</span>`, color, weight, html.EscapeString(text))
+	case ModeTmux:
+		// Style segments for status-right, '#' must be doubled in the text
+		attr := ""
+		if class == "beep" {
+			attr = ",bold"
+		}
+		return fmt.Sprintf("#[fg=%s%s]%s#[default]", color, attr, strings.ReplaceAll(text, "#", "##"))
+	default:
+		return text
+	}
+}
+
+// parseI3blocksClick extracts the mouse button from a click event that
+// i3blocks writes to the stdin of persistent blocks. Both the JSON format
+// and a bare button number are accepted. Returns 0 if the line is not a click.
+func parseI3blocksClick(line string) int {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return 0
+	}
+	if strings.HasPrefix(line, "{") {
+		var click struct {
+			Button int `json:"button"`
+		}
+		if err := json.Unmarshal([]byte(line), &click); err != nil {
+			return 0
+		}
+		return click.Button
+	}
+	button, err := strconv.Atoi(line)
+	if err != nil {
+		return 0
+	}
+	return button
+}
+
+// defaultStatusFile returns the status file location shared by -tmux and
+// -query, preferring the per-user runtime directory.
+func defaultStatusFile() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "bleep.status")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("bleep-%d.status", os.Getuid()))
+}
+
+// writeStatusFile atomically replaces the status file with the given line,
+// so a concurrent -query never sees a partial write.
+func writeStatusFile(path, status string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".bleep-status-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(status + "\n"); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readStatusFile returns the latest status written by a running instance.
+// A missing or stale file yields an empty status rather than an error, so
+// tmux simply shows nothing when no timer is running.
+func readStatusFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if time.Since(info.ModTime()) > statusFileMaxAge {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
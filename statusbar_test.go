@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestFormatBarOutput tests the polybar, i3blocks and tmux formatting
+func TestFormatBarOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     OutputMode
+		pid      int
+		class    string
+		text     string
+		expected string
+	}{
+		{
+			name:     "polybar with click action",
+			mode:     ModePolybar,
+			pid:      1234,
+			class:    "counting",
+			text:     "24m 35s",
+			expected: "%{A1:kill -USR1 1234:}%{F#a6e3a1}24m 35s%{F-}%{A}",
+		},
+		{
+			name:     "polybar without pid",
+			mode:     ModePolybar,
+			class:    "paused",
+			text:     "Paused",
+			expected: "%{F#f9e2af}Paused%{F-}",
+		},
+		{
+			name:     "polybar escapes percent",
+			mode:     ModePolybar,
+			class:    "counting",
+			text:     "50%",
+			expected: "%{F#a6e3a1}50%%%{F-}",
+		},
+		{
+			name:     "i3blocks counting",
+			mode:     ModeI3blocks,
+			class:    "counting",
+			text:     "24m 35s",
+			expected: `<span foreground="#a6e3a1">24m 35s</span>`,
+		},
+		{
+			name:     "i3blocks beep is bold and escaped",
+			mode:     ModeI3blocks,
+			class:    "beep",
+			text:     "<BEEP>",
+			expected: `<span foreground="#f38ba8" weight="bold">&lt;BEEP&gt;</span>`,
+		},
+		{
+			name:     "tmux counting",
+			mode:     ModeTmux,
+			class:    "counting",
+			text:     "24m 35s",
+			expected: "#[fg=#a6e3a1]24m 35s#[default]",
+		},
+		{
+			name:     "tmux beep escapes hash",
+			mode:     ModeTmux,
+			class:    "beep",
+			text:     "#1",
+			expected: "#[fg=#f38ba8,bold]##1#[default]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := OutputConfig{Mode: tt.mode, PID: tt.pid}
+			result := formatBarOutput(config, tt.class, tt.text)
+			if result != tt.expected {
+				t.Errorf("formatBarOutput() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestFormatOutputBarModes tests that the Format functions route the bar modes
+func TestFormatOutputBarModes(t *testing.T) {
+	config := OutputConfig{
+		Mode:          ModeTmux,
+		MinutesList:   []int{25},
+		SecondsList:   []int{0},
+		IntervalCount: 1,
+	}
+	timestamp := time.Date(2024, 12, 13, 15, 30, 0, 0, time.Local)
+
+	if result := FormatTickOutput(config, 24*time.Minute+35*time.Second, 0); result != "#[fg=#a6e3a1]24m 35s#[default]" {
+		t.Errorf("FormatTickOutput() = %q", result)
+	}
+	if result := FormatPausedOutput(config, time.Minute); result != "#[fg=#f9e2af]Paused#[default]" {
+		t.Errorf("FormatPausedOutput() = %q", result)
+	}
+	if result := FormatBeepOutput(config, 1, "automatic", 0, timestamp); result != "#[fg=#f38ba8,bold]BEEP#[default]" {
+		t.Errorf("FormatBeepOutput() = %q", result)
+	}
+	if result := FormatResetOutput(config, 0, timestamp); result != "" {
+		t.Errorf("FormatResetOutput() = %q, want empty", result)
+	}
+}
+
+// TestOutputModeLineOriented tests the lineOriented method
+func TestOutputModeLineOriented(t *testing.T) {
+	for _, mode := range []OutputMode{ModeJSON, ModeWatch, ModePolybar, ModeI3blocks, ModeTmux} {
+		if !mode.lineOriented() {
+			t.Errorf("mode %v: expected line oriented", mode)
+		}
+	}
+	for _, mode := range []OutputMode{ModeDefault, ModeVerbose} {
+		if mode.lineOriented() {
+			t.Errorf("mode %v: expected not line oriented", mode)
+		}
+	}
+}
+
+// TestParseI3blocksClick tests the parseI3blocksClick function
+func TestParseI3blocksClick(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int
+	}{
+		{name: "json left click", input: `{"name":"bleep","button":1,"x":10,"y":5}`, expected: 1},
+		{name: "json right click", input: `{"button":3}`, expected: 3},
+		{name: "bare number", input: "1\n", expected: 1},
+		{name: "empty line", input: "", expected: 0},
+		{name: "garbage", input: "hello", expected: 0},
+		{name: "broken json", input: `{"button":`, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := parseI3blocksClick(tt.input); result != tt.expected {
+				t.Errorf("parseI3blocksClick(%q) = %d, want %d", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestStatusFile tests writing and reading the tmux status file
+func TestStatusFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bleep.status")
+
+	t.Run("missing file is empty", func(t *testing.T) {
+		status, err := readStatusFile(path)
+		if err != nil {
+			t.Fatalf("readStatusFile error: %v", err)
+		}
+		if status != "" {
+			t.Errorf("expected empty status, got %q", status)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		if err := writeStatusFile(path, "#[fg=#a6e3a1]1m 0s#[default]"); err != nil {
+			t.Fatalf("writeStatusFile error: %v", err)
+		}
+		status, err := readStatusFile(path)
+		if err != nil {
+			t.Fatalf("readStatusFile error: %v", err)
+		}
+		if status != "#[fg=#a6e3a1]1m 0s#[default]" {
+			t.Errorf("status = %q", status)
+		}
+	})
+
+	t.Run("stale file is empty", func(t *testing.T) {
+		old := time.Now().Add(-time.Minute)
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatalf("Chtimes error: %v", err)
+		}
+		status, err := readStatusFile(path)
+		if err != nil {
+			t.Fatalf("readStatusFile error: %v", err)
+		}
+		if status != "" {
+			t.Errorf("expected stale status to be empty, got %q", status)
+		}
+	})
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// lapTooltipLimit is the number of laps listed in the Waybar tooltip
+const lapTooltipLimit = 10
+
+// Lap is one lap recorded by the stopwatch
+type Lap struct {
+	Number int
+	Time   time.Duration // length of the lap
+	Total  time.Duration // elapsed time when the lap was recorded
+}
+
+// Stopwatch counts up from its start, leaving out the time spent paused.
+// With Every set it also beeps each time that much time has elapsed.
+type Stopwatch struct {
+	Every     time.Duration // beep interval, 0 for none
+	BeepCount int
+	Paused    bool
+	Laps      []Lap
+	Offset    time.Duration // elapsed time before Resumed
+	Resumed   time.Time     // when the stopwatch last started running
+	NextBeep  time.Duration // elapsed time of the next beep
+	LapStart  time.Duration // elapsed time when the current lap started
+}
+
+// NewStopwatch creates a stopwatch starting now
+func NewStopwatch(every time.Duration, startPaused bool) *Stopwatch {
+	return &Stopwatch{
+		Every:    every,
+		Paused:   startPaused,
+		Resumed:  time.Now(),
+		NextBeep: every,
+	}
+}
+
+// Elapsed returns the time counted so far
+func (sw *Stopwatch) Elapsed() time.Duration {
+	if sw.Paused {
+		return sw.Offset
+	}
+	return sw.Offset + time.Since(sw.Resumed)
+}
+
+// TogglePause toggles the pause state and returns the new pause state
+func (sw *Stopwatch) TogglePause() bool {
+	if sw.Paused {
+		sw.Paused = false
+		sw.Resumed = time.Now()
+	} else {
+		sw.Offset = sw.Elapsed()
+		sw.Paused = true
+	}
+	return sw.Paused
+}
+
+// LapTime returns the time counted in the current lap
+func (sw *Stopwatch) LapTime() time.Duration {
+	return sw.Elapsed() - sw.LapStart
+}
+
+// Lap records the current lap and starts the next one
+func (sw *Stopwatch) Lap() Lap {
+	elapsed := sw.Elapsed()
+	lap := Lap{
+		Number: len(sw.Laps) + 1,
+		Time:   elapsed - sw.LapStart,
+		Total:  elapsed,
+	}
+	sw.Laps = append(sw.Laps, lap)
+	sw.LapStart = elapsed
+	return lap
+}
+
+// Reset sets the stopwatch back to zero and clears the laps. A paused
+// stopwatch stays paused.
+func (sw *Stopwatch) Reset() {
+	sw.Offset = 0
+	sw.Resumed = time.Now()
+	sw.Laps = nil
+	sw.LapStart = 0
+	sw.NextBeep = sw.Every
+}
+
+// BeepDue reports whether the elapsed time reached the next beep
+func (sw *Stopwatch) BeepDue() bool {
+	return sw.Every > 0 && sw.Elapsed() >= sw.NextBeep
+}
+
+// TriggerBeep increments the beep count and schedules the next beep. Beeps
+// that are overdue as well, e.g. after a suspend, are dropped.
+func (sw *Stopwatch) TriggerBeep() {
+	sw.BeepCount++
+	sw.NextBeep += sw.Every
+	sw.SkipMissed()
+}
+
+// Remaining returns the time until the next beep, or 0 without beeps
+func (sw *Stopwatch) Remaining() time.Duration {
+	if sw.Every == 0 {
+		return 0
+	}
+	return max(sw.NextBeep-sw.Elapsed(), 0)
+}
+
+// NewStopwatchEvent creates an event describing the stopwatch
+func NewStopwatchEvent(eventType EventType, config OutputConfig, sw *Stopwatch, timestamp time.Time) Event {
+	return Event{
+		Version:   EventSchemaVersion,
+		Type:      eventType,
+		Timestamp: timestamp,
+		Segment:   1,
+		Segments:  1,
+		Label:     labelFor(config.Labels, 0),
+		Remaining: int(sw.Remaining().Round(time.Second).Seconds()),
+		BeepCount: sw.BeepCount,
+		Paused:    sw.Paused,
+		Stopwatch: true,
+		Elapsed:   int(sw.Elapsed().Round(time.Second).Seconds()),
+		Lap:       len(sw.Laps) + 1,
+		LapTime:   int(sw.LapTime().Round(time.Second).Seconds()),
+	}
+}
+
+// NewStopwatchTemplateData builds template data from the stopwatch
+func NewStopwatchTemplateData(config OutputConfig, sw *Stopwatch, state string) TemplateData {
+	data := TemplateData{
+		Remaining: sw.Remaining(),
+		Elapsed:   sw.Elapsed(),
+		Interval:  sw.Every,
+		Label:     labelFor(config.Labels, 0),
+		Index:     1,
+		Count:     1,
+		BeepCount: sw.BeepCount,
+		State:     state,
+		Lap:       len(sw.Laps) + 1,
+		LapTime:   sw.LapTime(),
+	}
+	if sw.Every > 0 {
+		data.Percent = int((sw.Every - data.Remaining) * 100 / sw.Every)
+	}
+	return data
+}
+
+// lapTooltip lists the laps for the Waybar tooltip, most recent first
+func lapTooltip(laps []Lap, data TemplateData) string {
+	if len(laps) == 0 {
+		if data.Interval > 0 {
+			return fmt.Sprintf("Stopwatch, beeping every %s", formatDuration(data.Interval))
+		}
+		return "Stopwatch"
+	}
+	lines := []string{fmt.Sprintf("Lap %d: %s", data.Lap, formatClock(data.LapTime))}
+	for i := len(laps) - 1; i >= 0 && len(lines) < lapTooltipLimit; i-- {
+		lines = append(lines, fmt.Sprintf("Lap %d: %s", laps[i].Number, formatClock(laps[i].Time)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatStopwatchOutput returns the output string for the stopwatch. States
+// are counting, paused, lap, reset, beep, warning, alarm and finished.
+func FormatStopwatchOutput(config OutputConfig, data TemplateData, laps []Lap, timestamp time.Time) string {
+	if config.Template != nil {
+		return formatTemplateOutput(config, data, timestamp)
+	}
+	if data.State == "beep" {
+		return FormatBeepOutput(config, data.BeepCount, data.BeepType, 0, timestamp)
+	}
+	if data.State == "finished" {
+		return FormatFinishedOutput(config, data.BeepCount)
+	}
+
+	elapsed := formatClock(data.Elapsed)
+	class := "counting"
+	switch {
+	case data.State == "alarm" || data.Alarm:
+		class = "alarm"
+	case data.State == "paused":
+		class = "paused"
+	case data.State == "warning" || data.Warning:
+		class = "warning"
+	}
+
+	switch config.Mode {
+	case ModeJSON:
+		output := WaybarOutput{
+			Text:      elapsed,
+			Tooltip:   lapTooltip(laps, data),
+			Class:     class,
+			Remaining: int(data.Remaining.Round(time.Second).Seconds()),
+			Elapsed:   int(data.Elapsed.Round(time.Second).Seconds()),
+		}
+		jsonBytes, _ := json.Marshal(output)
+		return string(jsonBytes)
+	case ModeWatch:
+		if data.State == "paused" {
+			return "PAUSED " + elapsed
+		}
+		return elapsed
+	case ModePolybar, ModeI3blocks, ModeTmux:
+		return formatBarOutput(config, class, elapsed)
+	case ModeVerbose:
+		switch data.State {
+		case "lap":
+			lap := laps[len(laps)-1]
+			return fmt.Sprintf("\r[%s] Lap %d: %s (total %s)              \n",
+				timestamp.Format("15:04:05"), lap.Number, formatClock(lap.Time), formatClock(lap.Total))
+		case "reset":
+			return fmt.Sprintf("\r[%s] Stopwatch reset              \n", timestamp.Format("15:04:05"))
+		case "warning":
+			return fmt.Sprintf("\r[%s] Warning: %s to the next beep              \n", timestamp.Format("15:04:05"), formatDuration(data.Remaining))
+		case "alarm":
+			return FormatAlarmOutput(config, data.AlarmBeeps, timestamp)
+		case "paused":
+			return fmt.Sprintf("\rPaused - %s elapsed ", elapsed)
+		}
+		if len(laps) == 0 {
+			return fmt.Sprintf("\rElapsed: %s ", elapsed)
+		}
+		return fmt.Sprintf("\rElapsed: %s | Lap %d: %s ", elapsed, data.Lap, formatClock(data.LapTime))
+	case ModeEvents:
+		return ""
+	default:
+		if data.State == "warning" {
+			return fmt.Sprintf("WARNING %s\n", timestamp.Format(time.RFC3339))
+		}
+		if data.State == "alarm" {
+			return FormatAlarmOutput(config, data.AlarmBeeps, timestamp)
+		}
+		if data.State != "lap" {
+			return ""
+		}
+		lap := laps[len(laps)-1]
+		return fmt.Sprintf("LAP %d %s %s\n", lap.Number, formatClock(lap.Time), timestamp.Format(time.RFC3339))
+	}
+}
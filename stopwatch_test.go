@@ -0,0 +1,152 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestStopwatch returns a running stopwatch that started elapsed ago
+func newTestStopwatch(every, elapsed time.Duration) *Stopwatch {
+	sw := NewStopwatch(every, false)
+	sw.Resumed = time.Now().Add(-elapsed)
+	return sw
+}
+
+// TestStopwatch tests counting, pausing and laps
+func TestStopwatch(t *testing.T) {
+	sw := newTestStopwatch(0, 90*time.Second)
+
+	if elapsed := sw.Elapsed(); elapsed < 90*time.Second || elapsed > 91*time.Second {
+		t.Errorf("Elapsed() = %v, expected about 90s", elapsed)
+	}
+
+	lap := sw.Lap()
+	if lap.Number != 1 || lap.Time.Round(time.Second) != 90*time.Second {
+		t.Errorf("first lap = %+v", lap)
+	}
+
+	// The paused time is not counted
+	sw.Resumed = sw.Resumed.Add(-30 * time.Second)
+	if !sw.TogglePause() {
+		t.Fatal("TogglePause() should pause")
+	}
+	paused := sw.Elapsed()
+	time.Sleep(20 * time.Millisecond)
+	if sw.Elapsed() != paused {
+		t.Error("Elapsed() changed while paused")
+	}
+	sw.TogglePause()
+
+	lap = sw.Lap()
+	if lap.Number != 2 || lap.Time.Round(time.Second) != 30*time.Second || lap.Total.Round(time.Second) != 120*time.Second {
+		t.Errorf("second lap = %+v", lap)
+	}
+	if sw.LapTime() > time.Second {
+		t.Errorf("LapTime() = %v after a lap, expected about 0", sw.LapTime())
+	}
+
+	sw.Reset()
+	if sw.Elapsed() > time.Second || len(sw.Laps) != 0 {
+		t.Errorf("after Reset: Elapsed() = %v, %d laps", sw.Elapsed(), len(sw.Laps))
+	}
+}
+
+// TestStopwatchBeeps tests beeps every N of elapsed time
+func TestStopwatchBeeps(t *testing.T) {
+	if newTestStopwatch(0, time.Hour).BeepDue() {
+		t.Error("stopwatch without interval should never beep")
+	}
+
+	sw := newTestStopwatch(time.Minute, 30*time.Second)
+	if sw.BeepDue() {
+		t.Error("beep due after 30s with a 1m interval")
+	}
+	if remaining := sw.Remaining().Round(time.Second); remaining != 30*time.Second {
+		t.Errorf("Remaining() = %v, want 30s", remaining)
+	}
+
+	sw.Resumed = sw.Resumed.Add(-30 * time.Second)
+	if !sw.BeepDue() {
+		t.Fatal("beep not due after 1m")
+	}
+	sw.TriggerBeep()
+	if sw.BeepDue() || sw.BeepCount != 1 || sw.NextBeep != 2*time.Minute {
+		t.Errorf("after beep: due %v, count %d, next %v", sw.BeepDue(), sw.BeepCount, sw.NextBeep)
+	}
+
+	// After a reset the schedule starts over
+	sw.Reset()
+	if sw.NextBeep != time.Minute {
+		t.Errorf("NextBeep after Reset = %v, want 1m", sw.NextBeep)
+	}
+}
+
+// TestFormatStopwatchOutput tests the stopwatch output in each mode
+func TestFormatStopwatchOutput(t *testing.T) {
+	timestamp := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	laps := []Lap{
+		{Number: 1, Time: 60 * time.Second, Total: 60 * time.Second},
+		{Number: 2, Time: 65 * time.Second, Total: 125 * time.Second},
+	}
+	counting := TemplateData{Elapsed: 145 * time.Second, State: "counting", Index: 1, Count: 1, Lap: 3, LapTime: 20 * time.Second}
+	lapped := counting
+	lapped.State = "lap"
+	lapped.LapTime = 0
+	paused := counting
+	paused.State = "paused"
+
+	tests := []struct {
+		name     string
+		mode     OutputMode
+		data     TemplateData
+		laps     []Lap
+		expected string
+	}{
+		{"json counting", ModeJSON, counting, laps,
+			`{"text":"02:25","tooltip":"Lap 3: 00:20\nLap 2: 01:05\nLap 1: 01:00","class":"counting","remaining":0,"elapsed":145}`},
+		{"json no laps", ModeJSON, counting, nil,
+			`{"text":"02:25","tooltip":"Stopwatch","class":"counting","remaining":0,"elapsed":145}`},
+		{"json paused", ModeJSON, paused, nil,
+			`{"text":"02:25","tooltip":"Stopwatch","class":"paused","remaining":0,"elapsed":145}`},
+		{"watch", ModeWatch, counting, laps, "02:25"},
+		{"watch paused", ModeWatch, paused, laps, "PAUSED 02:25"},
+		{"tmux", ModeTmux, counting, laps, "#[fg=#a6e3a1]02:25#[default]"},
+		{"verbose counting", ModeVerbose, counting, laps, "\rElapsed: 02:25 | Lap 3: 00:20 "},
+		{"verbose no laps", ModeVerbose, counting, nil, "\rElapsed: 02:25 "},
+		{"verbose lap", ModeVerbose, lapped, laps, "\r[10:30:00] Lap 2: 01:05 (total 02:05)              \n"},
+		{"default lap", ModeDefault, lapped, laps, "LAP 2 01:05 2025-01-01T10:30:00Z\n"},
+		{"default tick", ModeDefault, counting, laps, ""},
+		{"events", ModeEvents, lapped, laps, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := OutputConfig{Mode: tt.mode, MinutesList: []int{0}, SecondsList: []int{0}, IntervalCount: 1}
+			if result := FormatStopwatchOutput(config, tt.data, tt.laps, timestamp); result != tt.expected {
+				t.Errorf("FormatStopwatchOutput() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestNewStopwatchEvent tests the stopwatch fields of events
+func TestNewStopwatchEvent(t *testing.T) {
+	sw := newTestStopwatch(5*time.Minute, 2*time.Minute)
+	sw.Lap()
+	config := OutputConfig{Labels: []string{"run"}}
+
+	event := NewStopwatchEvent(EventTick, config, sw, time.Now())
+	if !event.Stopwatch || event.Elapsed != 120 || event.Lap != 2 || event.Remaining != 180 || event.Label != "run" {
+		t.Errorf("event = %+v", event)
+	}
+	if out := FormatEventOutput(event); !strings.Contains(out, `"stopwatch":true,"elapsed":120,"lap":2`) {
+		t.Errorf("FormatEventOutput() = %s", out)
+	}
+
+	// Countdown events don't carry the stopwatch fields
+	ts := NewTimerState([]time.Duration{time.Minute}, []int{1}, []int{0}, false)
+	if out := FormatEventOutput(NewEvent(EventTick, config, ts, time.Now())); strings.Contains(out, "elapsed") {
+		t.Errorf("countdown event has stopwatch fields: %s", out)
+	}
+}
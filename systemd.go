@@ -0,0 +1,278 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listenFDsStart is the first file descriptor passed by socket activation
+const listenFDsStart = 3
+
+// systemdListener returns the socket passed by systemd socket activation,
+// or nil if bleep was not started by a .socket unit.
+func systemdListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+	// Don't pass the sockets on to hook commands
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(uintptr(listenFDsStart), "LISTEN_FD_3")
+	defer f.Close()
+	listener, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("using socket from systemd: %w", err)
+	}
+	return listener, nil
+}
+
+// ServiceNotifier reports the service state to systemd over NOTIFY_SOCKET
+// (sd_notify), so `systemctl --user status` shows the countdown.
+type ServiceNotifier struct {
+	conn *net.UnixConn
+}
+
+// NewServiceNotifier connects to NOTIFY_SOCKET, or returns nil if bleep is
+// not running as a Type=notify service.
+func NewServiceNotifier() (*ServiceNotifier, error) {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(path, "@") {
+		// Abstract socket namespace
+		path = "\x00" + path[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("connecting to NOTIFY_SOCKET: %w", err)
+	}
+	return &ServiceNotifier{conn: conn}, nil
+}
+
+// Notify sends state assignments such as "READY=1" or "STATUS=..."
+func (n *ServiceNotifier) Notify(state ...string) error {
+	_, err := n.conn.Write([]byte(strings.Join(state, "\n")))
+	return err
+}
+
+// Close closes the connection to systemd
+func (n *ServiceNotifier) Close() error {
+	return n.conn.Close()
+}
+
+// watchdogInterval returns how often systemd expects WATCHDOG=1, or 0 if
+// the watchdog is disabled
+func watchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// serviceStatus returns the STATUS= text for an event
+func serviceStatus(event Event) string {
+	name := fmt.Sprintf("Interval %d/%d", event.Segment, event.Segments)
+	if event.Label != "" {
+		name = event.Label
+	}
+	if event.Stopwatch {
+		if event.Label == "" {
+			name = "Stopwatch"
+		}
+		state := ""
+		if event.Paused {
+			state = "paused, "
+		}
+		return fmt.Sprintf("%s: %s%s elapsed, lap %d (%d beeps)", name, state, formatDuration(secondsDuration(event.Elapsed)), event.Lap, event.BeepCount)
+	}
+	if event.Paused {
+		return fmt.Sprintf("%s: paused, %s left (%d beeps)", name, formatDuration(secondsDuration(event.Remaining)), event.BeepCount)
+	}
+	return fmt.Sprintf("%s: %s left (%d beeps)", name, formatDuration(secondsDuration(event.Remaining)), event.BeepCount)
+}
+
+// serviceUnit holds the values of the generated unit files
+type serviceUnit struct {
+	Name   string
+	Exec   []string
+	Socket string // ListenStream= of the .socket unit, "" for none
+}
+
+// Service returns the .service unit
+func (u serviceUnit) Service() string {
+	quoted := make([]string, len(u.Exec))
+	for i, arg := range u.Exec {
+		quoted[i] = quoteUnitArg(arg)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\nDescription=bleep interval timer\n")
+	if u.Socket != "" {
+		fmt.Fprintf(&b, "Requires=%s.socket\nAfter=%s.socket\n", u.Name, u.Name)
+	}
+	fmt.Fprintf(&b, "\n[Service]\nType=notify\nExecStart=%s\nWatchdogSec=30s\nRestart=on-failure\nSuccessExitStatus=130 143\n", strings.Join(quoted, " "))
+	fmt.Fprintf(&b, "\n[Install]\nWantedBy=default.target\n")
+	return b.String()
+}
+
+// SocketUnit returns the .socket unit for the HTTP API
+func (u serviceUnit) SocketUnit() string {
+	return fmt.Sprintf("[Unit]\nDescription=bleep HTTP API socket\n\n[Socket]\nListenStream=%s\n\n[Install]\nWantedBy=sockets.target\n", u.Socket)
+}
+
+// quoteUnitArg quotes a command line argument for ExecStart=
+func quoteUnitArg(arg string) string {
+	// Specifiers and variables would otherwise be expanded by systemd
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	arg = strings.ReplaceAll(arg, "$", "$$")
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+		return arg
+	}
+	arg = strings.ReplaceAll(arg, `\`, `\\`)
+	arg = strings.ReplaceAll(arg, `"`, `\"`)
+	return `"` + arg + `"`
+}
+
+// defaultUnitDir returns the directory for systemd user units
+func defaultUnitDir() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "systemd", "user")
+}
+
+// runInstallService implements `bleep install-service [flags] [-- timer flags]`.
+// timerFlags are the flags of the timer, to tell flags from their values in
+// the timer command line.
+func runInstallService(args []string, timerFlags *flag.FlagSet, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("install-service", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	name := fs.String("name", "bleep", "unit name")
+	dir := fs.String("dir", defaultUnitDir(), "directory to write the units to")
+	socket := fs.String("socket", "", "also create a .socket unit for the HTTP API listening on this address (e.g. 127.0.0.1:7777)")
+	force := fs.Bool("force", false, "overwrite existing units")
+	printOnly := fs.Bool("print", false, "print the units instead of writing them")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: bleep install-service [flags] [-- timer flags]\n\n")
+		fmt.Fprintf(stderr, "Writes a systemd user unit that runs bleep with the given timer flags.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	timerArgs := fs.Args()
+	if !hasFlag(timerFlags, timerArgs, "log") {
+		timerArgs = append([]string{"-log", LogJournald}, timerArgs...)
+	}
+	unit := serviceUnit{
+		Name:   *name,
+		Exec:   append([]string{exe}, timerArgs...),
+		Socket: *socket,
+	}
+
+	files := map[string]string{*name + ".service": unit.Service()}
+	if *socket != "" {
+		files[*name+".socket"] = unit.SocketUnit()
+	}
+	order := []string{*name + ".service", *name + ".socket"}
+
+	if *printOnly {
+		for _, file := range order {
+			if content, ok := files[file]; ok {
+				fmt.Fprintf(stdout, "# %s\n%s\n", file, content)
+			}
+		}
+		return 0
+	}
+
+	if *dir == "" {
+		fmt.Fprintf(stderr, "Error: cannot determine the unit directory, use -dir\n")
+		return 1
+	}
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, file := range order {
+		content, ok := files[file]
+		if !ok {
+			continue
+		}
+		path := filepath.Join(*dir, file)
+		if _, err := os.Stat(path); err == nil && !*force {
+			fmt.Fprintf(stderr, "Error: %s already exists (use -force to overwrite)\n", path)
+			return 1
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Wrote %s\n", path)
+	}
+
+	fmt.Fprintf(stdout, "\nStart it with:\n  systemctl --user daemon-reload\n")
+	if *socket != "" {
+		fmt.Fprintf(stdout, "  systemctl --user enable --now %s.socket %s.service\n", *name, *name)
+	} else {
+		fmt.Fprintf(stdout, "  systemctl --user enable --now %s.service\n", *name)
+	}
+	return 0
+}
+
+// hasFlag reports whether a command line for the flags sets the named flag.
+// Values of flags that take one are skipped, so "-format log" doesn't count.
+func hasFlag(flags *flag.FlagSet, args []string, name string) bool {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") || arg == "-" {
+			return false
+		}
+		arg, _, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+		if arg == name {
+			return true
+		}
+		if f := flags.Lookup(arg); f != nil && !hasValue && !isBoolFlag(f) {
+			i++
+		}
+	}
+	return false
+}
+
+// isBoolFlag reports whether the flag is a switch that takes no value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
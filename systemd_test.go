@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestQuoteUnitArg tests the quoteUnitArg function
+func TestQuoteUnitArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"-m", "-m"},
+		{"25,5", "25,5"},
+		{"", `""`},
+		{"{{.Label}} {{clock .Remaining}}", `"{{.Label}} {{clock .Remaining}}"`},
+		{`say "done"`, `"say \"done\""`},
+		{"100%", "100%%"},
+		{"$HOME/beep.sh", "$$HOME/beep.sh"},
+	}
+
+	for _, tt := range tests {
+		if got := quoteUnitArg(tt.arg); got != tt.want {
+			t.Errorf("quoteUnitArg(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
+
+// TestServiceUnit tests the generated unit files
+func TestServiceUnit(t *testing.T) {
+	unit := serviceUnit{
+		Name:   "pomodoro",
+		Exec:   []string{"/usr/bin/bleep", "-m", "25,5", "-labels", "work,break"},
+		Socket: "127.0.0.1:7777",
+	}
+
+	service := unit.Service()
+	for _, want := range []string{
+		"Type=notify\n",
+		"ExecStart=/usr/bin/bleep -m 25,5 -labels work,break\n",
+		"WatchdogSec=",
+		"SuccessExitStatus=130 143\n",
+		"Requires=pomodoro.socket\n",
+		"WantedBy=default.target\n",
+	} {
+		if !strings.Contains(service, want) {
+			t.Errorf("service unit missing %q:\n%s", want, service)
+		}
+	}
+	if socket := unit.SocketUnit(); !strings.Contains(socket, "ListenStream=127.0.0.1:7777\n") {
+		t.Errorf("socket unit missing ListenStream:\n%s", socket)
+	}
+
+	unit.Socket = ""
+	if strings.Contains(unit.Service(), "Requires=") {
+		t.Error("service without socket should not require one")
+	}
+}
+
+// TestRunInstallService tests writing the units
+func TestRunInstallService(t *testing.T) {
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+
+	code := runInstallService([]string{"-dir", dir, "-socket", "127.0.0.1:7777", "--", "-m", "25"}, testTimerFlags(), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr: %s", code, stderr.String())
+	}
+	service, err := os.ReadFile(filepath.Join(dir, "bleep.service"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(service), " -log journald -m 25\n") {
+		t.Errorf("ExecStart should log to journald by default:\n%s", service)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bleep.socket")); err != nil {
+		t.Errorf("socket unit not written: %v", err)
+	}
+
+	// Existing units are kept unless -force is given
+	if code := runInstallService([]string{"-dir", dir}, testTimerFlags(), &stdout, &stderr); code != 1 {
+		t.Errorf("exit code = %d, want 1 for existing unit", code)
+	}
+	if code := runInstallService([]string{"-dir", dir, "-force", "--", "-m", "50", "-log=text"}, testTimerFlags(), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d with -force", code)
+	}
+	service, _ = os.ReadFile(filepath.Join(dir, "bleep.service"))
+	if strings.Contains(string(service), "journald") {
+		t.Errorf("explicit -log should be kept:\n%s", service)
+	}
+}
+
+// TestServiceNotifier tests sd_notify messages
+func TestServiceNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	t.Setenv("NOTIFY_SOCKET", "")
+	if n, err := NewServiceNotifier(); n != nil || err != nil {
+		t.Fatalf("NewServiceNotifier() without NOTIFY_SOCKET = %v, %v", n, err)
+	}
+
+	t.Setenv("NOTIFY_SOCKET", path)
+	n, err := NewServiceNotifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	if err := n.Notify("READY=1", "STATUS=work: 25m 0s left (0 beeps)"); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	size, err := server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:size]); got != "READY=1\nSTATUS=work: 25m 0s left (0 beeps)" {
+		t.Errorf("datagram = %q", got)
+	}
+}
+
+// TestWatchdogInterval tests the watchdogInterval function
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", "")
+	if got := watchdogInterval(); got != 30*time.Second {
+		t.Errorf("watchdogInterval() = %v, want 30s", got)
+	}
+
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+	if got := watchdogInterval(); got != 0 {
+		t.Errorf("watchdogInterval() for another PID = %v, want 0", got)
+	}
+
+	t.Setenv("WATCHDOG_PID", "")
+	t.Setenv("WATCHDOG_USEC", "")
+	if got := watchdogInterval(); got != 0 {
+		t.Errorf("watchdogInterval() without WATCHDOG_USEC = %v, want 0", got)
+	}
+}
+
+// TestServiceStatus tests the serviceStatus function
+func TestServiceStatus(t *testing.T) {
+	tests := []struct {
+		event Event
+		want  string
+	}{
+		{Event{Segment: 1, Segments: 2, Label: "work", Remaining: 1475, BeepCount: 2}, "work: 24m 35s left (2 beeps)"},
+		{Event{Segment: 2, Segments: 2, Remaining: 45}, "Interval 2/2: 45s left (0 beeps)"},
+		{Event{Segment: 1, Segments: 1, Label: "tea", Remaining: 60, Paused: true}, "tea: paused, 1m 0s left (0 beeps)"},
+	}
+
+	for _, tt := range tests {
+		if got := serviceStatus(tt.event); got != tt.want {
+			t.Errorf("serviceStatus() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// testTimerFlags returns a few of the timer flags for install-service
+func testTimerFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("bleep", flag.ContinueOnError)
+	fs.String("m", "0", "")
+	fs.String("format", "", "")
+	fs.String("log", LogText, "")
+	fs.String("log-level", "", "")
+	fs.Bool("v", false, "")
+	return fs
+}
+
+// TestHasFlag tests the hasFlag function
+func TestHasFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"-m", "25", "-log", "text"}, true},
+		{[]string{"--log=json"}, true},
+		{[]string{"-v", "-log", "syslog"}, true},
+		{[]string{"-log-level", "debug"}, false},
+		{[]string{"-m", "25", "--", "-log"}, false},
+		// Flag values and positional arguments aren't flags
+		{[]string{"-format", "log"}, false},
+		{[]string{"-format", "-log"}, false},
+		{[]string{"-format=x", "log"}, false},
+		{[]string{"log"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := hasFlag(testTimerFlags(), tt.args, "log"); got != tt.want {
+			t.Errorf("hasFlag(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
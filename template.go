@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData holds the fields available to -format templates
+type TemplateData struct {
+	Remaining  time.Duration // time left in the current interval
+	Elapsed    time.Duration // time spent in the current interval
+	Interval   time.Duration // length of the current interval
+	Label      string        // label of the current interval, if any
+	Index      int           // 1-based position of the current interval
+	Count      int           // number of intervals in the rotation
+	BeepCount  int           // beeps so far
+	BeepType   string        // "automatic", "manual" or "missed", only set on beeps
+	State      string        // counting, paused, beep, reset, lap, warning, alarm or finished
+	Warning    bool          // within the -warn period before the beep
+	Alarm      bool          // an -alarm is sounding, until acknowledged
+	AlarmBeeps int           // beeps played by the sounding alarm
+	Percent    int           // elapsed share of the current interval, 0-100
+	Lap        int           // stopwatch only: number of the current lap
+	LapTime    time.Duration // stopwatch only: time counted in the current lap
+}
+
+// NewTemplateData builds template data from the timer state
+func NewTemplateData(config OutputConfig, ts *TimerState, state string) TemplateData {
+	interval := ts.CurrentInterval()
+	remaining := ts.Remaining()
+	if remaining < 0 {
+		remaining = 0
+	}
+	elapsed := interval - remaining
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return TemplateData{
+		Remaining: remaining,
+		Elapsed:   elapsed,
+		Interval:  interval,
+		Label:     labelFor(config.Labels, ts.IntervalIndex),
+		Index:     ts.IntervalIndex + 1,
+		Count:     len(ts.Intervals),
+		BeepCount: ts.BeepCount,
+		State:     state,
+		Percent:   int(elapsed * 100 / interval),
+	}
+}
+
+// class returns the output class for the state, "alarm" while an alarm is
+// sounding and "warning" for ticks within the -warn period
+func (d TemplateData) class() string {
+	if d.Alarm && (d.State == "counting" || d.State == "paused") {
+		return "alarm"
+	}
+	if d.State == "counting" && d.Warning {
+		return "warning"
+	}
+	return d.State
+}
+
+// templateFuncs are the helper functions available to -format templates
+var templateFuncs = template.FuncMap{
+	"duration": formatDuration,
+	"clock":    formatClock,
+	"seconds": func(d time.Duration) int {
+		return int(d.Round(time.Second).Seconds())
+	},
+	"minutes": func(d time.Duration) int {
+		return int(d.Round(time.Second).Minutes())
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"bar":   progressBar,
+}
+
+// parseFormatTemplate parses a -format template and checks that it executes
+// against sample data, so mistakes are reported at startup rather than on
+// every tick.
+func parseFormatTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	sample := TemplateData{
+		Remaining: 90 * time.Second,
+		Elapsed:   30 * time.Second,
+		Interval:  2 * time.Minute,
+		Index:     1,
+		Count:     1,
+		State:     "counting",
+		Percent:   25,
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// formatClock formats a duration as MM:SS, or H:MM:SS from one hour on
+func formatClock(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < 0 {
+		d = 0
+	}
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// progressBar renders percent as a bar of the given width
+func progressBar(percent, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	filled := percent * width / 100
+	filled = max(0, min(filled, width))
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// renderTemplate executes the -format template, falling back to the built-in
+// text if execution fails
+func renderTemplate(config OutputConfig, data TemplateData, fallback string) string {
+	var buf bytes.Buffer
+	if err := config.Template.Execute(&buf, data); err != nil {
+		return fallback
+	}
+	return buf.String()
+}
+
+// formatTemplateOutput shapes the rendered -format text the same way each
+// output mode shapes its built-in text
+func formatTemplateOutput(config OutputConfig, data TemplateData, timestamp time.Time) string {
+	fallback := config.duration(data.Remaining)
+	if data.Lap > 0 {
+		fallback = formatClock(data.Elapsed)
+	}
+	switch data.State {
+	case "paused":
+		fallback = "Paused"
+	case "beep":
+		fallback = "BEEP"
+	case "alarm":
+		fallback = "ALARM"
+	case "finished":
+		fallback = "Done"
+	}
+	text := renderTemplate(config, data, fallback)
+
+	switch config.Mode {
+	case ModeJSON:
+		output := WaybarOutput{
+			Text:      text,
+			Class:     data.class(),
+			Remaining: int(data.Remaining.Round(time.Second).Seconds()),
+		}
+		switch data.State {
+		case "paused":
+			output.Tooltip = "Click to start"
+		case "beep":
+			output.Tooltip = fmt.Sprintf("Beep #%d (%s)", data.BeepCount, data.BeepType)
+			output.Remaining = 0
+		case "alarm":
+			output.Tooltip = alarmTooltip(data.AlarmBeeps)
+			output.Remaining = 0
+		case "finished":
+			output.Tooltip = "Finished after " + plural(data.BeepCount, "beep")
+			output.Remaining = 0
+		case "reset":
+			return ""
+		default:
+			output.Tooltip = intervalTooltip(config, data.Index-1)
+			if data.Lap > 0 {
+				if data.State == "lap" {
+					output.Class = "counting"
+				}
+				output.Tooltip = fmt.Sprintf("Lap %d: %s", data.Lap, formatClock(data.LapTime))
+			}
+		}
+		jsonBytes, _ := json.Marshal(output)
+		return string(jsonBytes)
+	case ModeWatch, ModePolybar, ModeI3blocks, ModeTmux:
+		if data.State == "reset" {
+			return ""
+		}
+		if data.State == "lap" {
+			return formatBarOutput(config, "counting", text)
+		}
+		return formatBarOutput(config, data.class(), text)
+	case ModeVerbose:
+		if data.State == "finished" {
+			return ""
+		}
+		if data.State == "beep" || data.State == "reset" || data.State == "lap" || data.State == "warning" || data.State == "alarm" {
+			return fmt.Sprintf("\r[%s] %s              \n", timestamp.Format("15:04:05"), text)
+		}
+		return fmt.Sprintf("\r%s ", text)
+	case ModeEvents:
+		return ""
+	default:
+		if data.State != "beep" && data.State != "lap" && data.State != "warning" && data.State != "alarm" {
+			return ""
+		}
+		return text + "\n"
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestFormatClock tests the formatClock function
+func TestFormatClock(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "00:00"},
+		{45 * time.Second, "00:45"},
+		{5*time.Minute + 30*time.Second, "05:30"},
+		{time.Hour + 2*time.Minute + 3*time.Second, "1:02:03"},
+		{-5 * time.Second, "00:00"},
+	}
+
+	for _, tt := range tests {
+		if result := formatClock(tt.duration); result != tt.expected {
+			t.Errorf("formatClock(%v) = %q, want %q", tt.duration, result, tt.expected)
+		}
+	}
+}
+
+// TestProgressBar tests the progressBar function
+func TestProgressBar(t *testing.T) {
+	tests := []struct {
+		percent  int
+		width    int
+		expected string
+	}{
+		{0, 4, "░░░░"},
+		{50, 4, "██░░"},
+		{100, 4, "████"},
+		{150, 4, "████"},
+		{50, 0, ""},
+	}
+
+	for _, tt := range tests {
+		if result := progressBar(tt.percent, tt.width); result != tt.expected {
+			t.Errorf("progressBar(%d, %d) = %q, want %q", tt.percent, tt.width, result, tt.expected)
+		}
+	}
+}
+
+// TestNewTemplateData tests the NewTemplateData constructor
+func TestNewTemplateData(t *testing.T) {
+	intervals := []time.Duration{10 * time.Minute, 5 * time.Minute}
+	ts := NewTimerState(intervals, []int{10, 5}, []int{0, 0}, true)
+	ts.PausedAt = 4 * time.Minute
+	config := OutputConfig{Labels: []string{"work", "break"}}
+
+	data := NewTemplateData(config, ts, "paused")
+
+	if data.Remaining != 4*time.Minute {
+		t.Errorf("Remaining = %v, want 4m", data.Remaining)
+	}
+	if data.Elapsed != 6*time.Minute {
+		t.Errorf("Elapsed = %v, want 6m", data.Elapsed)
+	}
+	if data.Percent != 60 {
+		t.Errorf("Percent = %d, want 60", data.Percent)
+	}
+	if data.Label != "work" {
+		t.Errorf("Label = %q, want %q", data.Label, "work")
+	}
+	if data.Index != 1 || data.Count != 2 {
+		t.Errorf("Index/Count = %d/%d, want 1/2", data.Index, data.Count)
+	}
+	if data.State != "paused" {
+		t.Errorf("State = %q, want %q", data.State, "paused")
+	}
+}
+
+// TestParseFormatTemplate tests template parsing and validation
+func TestParseFormatTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		expectError bool
+	}{
+		{name: "plain fields", format: "{{.Label}} {{.Index}}/{{.Count}}"},
+		{name: "helper funcs", format: "{{clock .Remaining}} {{duration .Elapsed}} {{bar .Percent 10}}"},
+		{name: "syntax error", format: "{{.Remaining", expectError: true},
+		{name: "unknown field", format: "{{.Nope}}", expectError: true},
+		{name: "unknown func", format: "{{nope .Remaining}}", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseFormatTemplate(tt.format)
+			if tt.expectError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestFormatTemplateOutput tests that -format text is shaped per output mode
+func TestFormatTemplateOutput(t *testing.T) {
+	tmpl, err := parseFormatTemplate("{{.Label}} {{clock .Remaining}}")
+	if err != nil {
+		t.Fatalf("parseFormatTemplate error: %v", err)
+	}
+	timestamp := time.Date(2024, 12, 13, 15, 30, 0, 0, time.Local)
+	tick := TemplateData{
+		Remaining: 90 * time.Second,
+		Interval:  5 * time.Minute,
+		Label:     "work",
+		Index:     1,
+		Count:     1,
+		State:     "counting",
+	}
+	config := OutputConfig{
+		MinutesList:   []int{5},
+		SecondsList:   []int{0},
+		IntervalCount: 1,
+		Template:      tmpl,
+	}
+
+	t.Run("Watch mode", func(t *testing.T) {
+		config.Mode = ModeWatch
+		if result := FormatOutput(config, tick, timestamp); result != "work 01:30" {
+			t.Errorf("expected 'work 01:30', got %q", result)
+		}
+	})
+
+	t.Run("JSON mode", func(t *testing.T) {
+		config.Mode = ModeJSON
+		var output WaybarOutput
+		if err := json.Unmarshal([]byte(FormatOutput(config, tick, timestamp)), &output); err != nil {
+			t.Fatalf("json.Unmarshal error: %v", err)
+		}
+		if output.Text != "work 01:30" || output.Class != "counting" || output.Remaining != 90 {
+			t.Errorf("unexpected output %+v", output)
+		}
+		if output.Tooltip != "5m 0s" {
+			t.Errorf("Tooltip = %q, want %q", output.Tooltip, "5m 0s")
+		}
+	})
+
+	t.Run("Default mode only prints beeps", func(t *testing.T) {
+		config.Mode = ModeDefault
+		if result := FormatOutput(config, tick, timestamp); result != "" {
+			t.Errorf("expected empty tick output, got %q", result)
+		}
+		beep := tick
+		beep.State = "beep"
+		if result := FormatOutput(config, beep, timestamp); result != "work 01:30\n" {
+			t.Errorf("expected beep line, got %q", result)
+		}
+	})
+
+	t.Run("Verbose mode", func(t *testing.T) {
+		config.Mode = ModeVerbose
+		if result := FormatOutput(config, tick, timestamp); result != "\rwork 01:30 " {
+			t.Errorf("unexpected verbose output %q", result)
+		}
+	})
+
+	t.Run("Finished", func(t *testing.T) {
+		finished := tick
+		finished.State = "finished"
+		config.Mode = ModeWatch
+		if result := FormatOutput(config, finished, timestamp); result != "work 01:30" {
+			t.Errorf("expected 'work 01:30', got %q", result)
+		}
+		config.Mode = ModeVerbose
+		if result := FormatOutput(config, finished, timestamp); result != "" {
+			t.Errorf("expected no verbose output, got %q", result)
+		}
+	})
+
+	t.Run("Reset is silent outside verbose mode", func(t *testing.T) {
+		reset := tick
+		reset.State = "reset"
+		for _, mode := range []OutputMode{ModeDefault, ModeJSON, ModeWatch, ModeTmux} {
+			config.Mode = mode
+			if result := FormatOutput(config, reset, timestamp); result != "" {
+				t.Errorf("mode %v: expected empty string, got %q", mode, result)
+			}
+		}
+	})
+}
+
+// TestFormatOutputWithoutTemplate tests that FormatOutput keeps the built-in text
+func TestFormatOutputWithoutTemplate(t *testing.T) {
+	config := OutputConfig{
+		Mode:          ModeWatch,
+		MinutesList:   []int{25},
+		SecondsList:   []int{0},
+		IntervalCount: 1,
+	}
+	timestamp := time.Date(2024, 12, 13, 15, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		state    string
+		expected string
+	}{
+		{"counting", "24m 35s"},
+		{"paused", "PAUSED"},
+		{"beep", "BEEP"},
+		{"reset", ""},
+		{"finished", "DONE"},
+	}
+
+	for _, tt := range tests {
+		data := TemplateData{Remaining: 24*time.Minute + 35*time.Second, Index: 1, Count: 1, State: tt.state}
+		if result := FormatOutput(config, data, timestamp); result != tt.expected {
+			t.Errorf("state %s: FormatOutput() = %q, want %q", tt.state, result, tt.expected)
+		}
+	}
+}
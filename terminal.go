@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Terminal escape sequences used by TerminalNotifier
+const (
+	escBell      = "\a"
+	escPushTitle = "\x1b[22;0t" // XTWINOPS: save the window title
+	escPopTitle  = "\x1b[23;0t" // XTWINOPS: restore the saved window title
+)
+
+// TerminalNotifier signals timer events through terminal escape sequences:
+// desktop notifications via OSC 9 or OSC 777, the countdown in the window
+// title via OSC 0/2, and the bell, which most terminals turn into an urgent
+// hint on a background tab or window.
+type TerminalNotifier struct {
+	Out   io.Writer
+	OSC   string // "9" or "777" to send a notification on beeps, "" to disable
+	Title string // "0" (icon name and title) or "2" (title only), "" to disable
+	Bell  bool
+
+	titleSaved bool
+}
+
+// isTerminal reports whether f is connected to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// validateTerminalOptions checks the -osc-notify and -title values
+func validateTerminalOptions(osc, title string) error {
+	switch osc {
+	case "", "off", "9", "777":
+	default:
+		return fmt.Errorf("invalid -osc-notify value %q (want 9, 777 or off)", osc)
+	}
+	switch title {
+	case "", "off", "0", "2":
+	default:
+		return fmt.Errorf("invalid -title value %q (want 0, 2 or off)", title)
+	}
+	return nil
+}
+
+// Enabled reports whether any escape sequence is configured
+func (t *TerminalNotifier) Enabled() bool {
+	return t.OSC != "" || t.Title != "" || t.Bell
+}
+
+// Handle writes the escape sequences for an event
+func (t *TerminalNotifier) Handle(event Event) {
+	var b strings.Builder
+
+	if t.Title != "" {
+		if title := terminalTitle(event); title != "" {
+			if !t.titleSaved {
+				b.WriteString(escPushTitle)
+				t.titleSaved = true
+			}
+			fmt.Fprintf(&b, "\x1b]%s;%s\a", t.Title, sanitizeEscapeText(title))
+		}
+	}
+
+	if event.Type == EventBeep {
+		summary := fmt.Sprintf("Beep #%d", event.BeepCount)
+		if event.Label != "" {
+			summary += " (" + event.Label + " done)"
+		}
+		switch t.OSC {
+		case "9":
+			fmt.Fprintf(&b, "\x1b]9;bleep: %s\a", sanitizeEscapeText(summary))
+		case "777":
+			// Fields are separated by ';', so it must not appear in them
+			body := strings.ReplaceAll(sanitizeEscapeText(summary), ";", ",")
+			fmt.Fprintf(&b, "\x1b]777;notify;bleep;%s\a", body)
+		}
+		if t.Bell {
+			b.WriteString(escBell)
+		}
+	}
+
+	if b.Len() > 0 {
+		io.WriteString(t.Out, b.String())
+	}
+}
+
+// Restore gives the window its title back if bleep changed it
+func (t *TerminalNotifier) Restore() {
+	if t.titleSaved {
+		io.WriteString(t.Out, escPopTitle)
+		t.titleSaved = false
+	}
+}
+
+// terminalTitle returns the window title for an event, or "" to keep it
+func terminalTitle(event Event) string {
+	prefix := "bleep"
+	if event.Label != "" {
+		prefix = event.Label
+	}
+	shown := event.Remaining
+	if event.Stopwatch {
+		shown = event.Elapsed
+	}
+	switch event.Type {
+	case EventTick, EventResume, EventSegmentStart, EventReset:
+		if event.Paused {
+			return prefix + " (paused)"
+		}
+		return prefix + " " + formatDuration(secondsDuration(shown))
+	case EventPause:
+		return prefix + " (paused)"
+	default:
+		return ""
+	}
+}
+
+// sanitizeEscapeText removes control characters that would end or corrupt
+// an escape sequence
+func sanitizeEscapeText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestTerminalNotifierBeep tests the escape sequences written on beeps
+func TestTerminalNotifierBeep(t *testing.T) {
+	beep := Event{Type: EventBeep, BeepCount: 2, Label: "work"}
+
+	tests := []struct {
+		name     string
+		notifier TerminalNotifier
+		expected string
+	}{
+		{name: "OSC 9", notifier: TerminalNotifier{OSC: "9"}, expected: "\x1b]9;bleep: Beep #2 (work done)\a"},
+		{name: "OSC 777", notifier: TerminalNotifier{OSC: "777"}, expected: "\x1b]777;notify;bleep;Beep #2 (work done)\a"},
+		{name: "bell", notifier: TerminalNotifier{Bell: true}, expected: "\a"},
+		{name: "OSC 9 and bell", notifier: TerminalNotifier{OSC: "9", Bell: true}, expected: "\x1b]9;bleep: Beep #2 (work done)\a\a"},
+		{name: "disabled", notifier: TerminalNotifier{}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			tt.notifier.Out = &out
+			tt.notifier.Handle(beep)
+			if out.String() != tt.expected {
+				t.Errorf("output = %q, want %q", out.String(), tt.expected)
+			}
+		})
+	}
+}
+
+// TestTerminalNotifierTitle tests the countdown in the window title
+func TestTerminalNotifierTitle(t *testing.T) {
+	var out bytes.Buffer
+	notifier := &TerminalNotifier{Out: &out, Title: "2"}
+
+	notifier.Handle(Event{Type: EventTick, Remaining: 95})
+	expected := escPushTitle + "\x1b]2;bleep 1m 35s\a"
+	if out.String() != expected {
+		t.Errorf("first tick = %q, want %q", out.String(), expected)
+	}
+
+	out.Reset()
+	notifier.Handle(Event{Type: EventPause, Label: "work", Paused: true})
+	if out.String() != "\x1b]2;work (paused)\a" {
+		t.Errorf("pause = %q", out.String())
+	}
+
+	out.Reset()
+	notifier.Handle(Event{Type: EventBeep, BeepCount: 1})
+	if out.String() != "" {
+		t.Errorf("beep without OSC or bell should write nothing, got %q", out.String())
+	}
+
+	out.Reset()
+	notifier.Restore()
+	if out.String() != escPopTitle {
+		t.Errorf("Restore() = %q, want %q", out.String(), escPopTitle)
+	}
+
+	out.Reset()
+	notifier.Restore()
+	if out.String() != "" {
+		t.Errorf("second Restore() should write nothing, got %q", out.String())
+	}
+}
+
+// TestSanitizeEscapeText tests that control characters are removed
+func TestSanitizeEscapeText(t *testing.T) {
+	if result := sanitizeEscapeText("work\x1b]0;evil\a\n\u009b"); result != "work]0;evil" {
+		t.Errorf("sanitizeEscapeText() = %q", result)
+	}
+}
+
+// TestValidateTerminalOptions tests the -osc-notify and -title validation
+func TestValidateTerminalOptions(t *testing.T) {
+	valid := [][2]string{{"", ""}, {"9", "0"}, {"777", "2"}, {"off", "off"}}
+	for _, v := range valid {
+		if err := validateTerminalOptions(v[0], v[1]); err != nil {
+			t.Errorf("validateTerminalOptions(%q, %q) unexpected error: %v", v[0], v[1], err)
+		}
+	}
+	invalid := [][2]string{{"99", ""}, {"", "1"}}
+	for _, v := range invalid {
+		if err := validateTerminalOptions(v[0], v[1]); err == nil {
+			t.Errorf("validateTerminalOptions(%q, %q) expected error", v[0], v[1])
+		}
+	}
+}
+
+// TestIsTerminal tests that regular files are not terminals
+func TestIsTerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if isTerminal(f) {
+		t.Error("isTerminal() = true for a regular file")
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+//go:generate go run voicegen.go
+
+// voiceFiles holds the voice clips built into bleep: sound files in voice/,
+// named after the word they speak (five.wav, minutes.ogg, work.flac)
+//
+//go:embed voice
+var voiceFiles embed.FS
+
+const (
+	// voiceGap is the pause between the words of a phrase
+	voiceGap = 60 * time.Millisecond
+	// bareNumberLimit is the time up to which announcements are a bare
+	// number, for counting down the last seconds ("three", "two", "one")
+	bareNumberLimit = 10 * time.Second
+)
+
+var (
+	unitWords = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+	tensWords = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+)
+
+// numberWords returns the words for a number from 0 to 99
+func numberWords(n int) []string {
+	if n < 20 {
+		return []string{unitWords[n]}
+	}
+	words := []string{tensWords[n/10]}
+	if n%10 != 0 {
+		words = append(words, unitWords[n%10])
+	}
+	return words
+}
+
+// quantityWords returns e.g. "one minute" or "five minutes"
+func quantityWords(n int, unit string) []string {
+	if n != 1 {
+		unit += "s"
+	}
+	return append(numberWords(n), unit)
+}
+
+// spokenTime returns the words announcing the time left, e.g. "five
+// minutes left", or a bare number for the last seconds
+func spokenTime(d time.Duration) []string {
+	d = d.Round(time.Second)
+	if d <= bareNumberLimit {
+		return numberWords(int(d / time.Second))
+	}
+	h := int(d / time.Hour)
+	m := int(d % time.Hour / time.Minute)
+	s := int(d % time.Minute / time.Second)
+
+	var words []string
+	if h > 0 {
+		words = append(words, quantityWords(min(h, 99), "hour")...)
+	}
+	if m > 0 {
+		words = append(words, quantityWords(m, "minute")...)
+	}
+	if s > 0 && h == 0 {
+		words = append(words, quantityWords(s, "second")...)
+	}
+	return append(words, "left")
+}
+
+// speakFunc is the function called to speak words. It can be replaced in
+// tests to prevent actual sound playback.
+var speakFunc = speakImpl
+
+// voicePack holds the clips used by speak, loaded at startup
+var voicePack VoicePack
+
+// speak calls speakFunc to speak words
+func speak(words []string) {
+	speakFunc(words)
+}
+
+// speakImpl plays the clips of the words on the audio context, mixed with
+// any beep playing at the same time
+func speakImpl(words []string) {
+	pcm, err := voicePack.Phrase(words, audioSampleRate)
+	if err != nil {
+		onAudioError(err)
+		return
+	}
+	playPCM(bytes.NewReader(pcm), 1)
+}
+
+// VoicePack holds voice clips by word, as 16-bit stereo PCM at the audio
+// sample rate
+type VoicePack map[string][]byte
+
+// loadVoicePack reads the clips in fsys, in any format DecodeSound reads.
+// The file name without its extension is the word, in lower case.
+func loadVoicePack(fsys fs.FS, sampleRate int) (VoicePack, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	pack := make(VoicePack)
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := path.Ext(name)
+		if entry.IsDir() || !slices.Contains(soundExtensions, strings.ToLower(ext)) {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		sound, err := DecodeSound(data)
+		if err != nil {
+			return nil, fmt.Errorf("voice clip %s: %w", name, err)
+		}
+		pack[strings.ToLower(strings.TrimSuffix(name, ext))] = sound.PCM(sampleRate)
+	}
+	return pack, nil
+}
+
+// Missing returns the words the pack has no clips for
+func (v VoicePack) Missing(words []string) []string {
+	var missing []string
+	for _, word := range words {
+		if _, ok := v[word]; !ok {
+			missing = append(missing, word)
+		}
+	}
+	return missing
+}
+
+// Phrase joins the clips of the words with short pauses
+func (v VoicePack) Phrase(words []string, sampleRate int) ([]byte, error) {
+	if missing := v.Missing(words); len(missing) > 0 {
+		return nil, fmt.Errorf("no voice clip for %s", strings.Join(missing, ", "))
+	}
+	gap := make([]byte, int(voiceGap.Seconds()*float64(sampleRate))*4)
+	var pcm []byte
+	for i, word := range words {
+		if i > 0 {
+			pcm = append(pcm, gap...)
+		}
+		pcm = append(pcm, v[word]...)
+	}
+	return pcm, nil
+}
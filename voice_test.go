@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// TestSpokenTime tests the words announcing the time left
+func TestSpokenTime(t *testing.T) {
+	tests := []struct {
+		remaining time.Duration
+		expected  string
+	}{
+		{3 * time.Second, "three"},
+		{2900 * time.Millisecond, "three"},
+		{10 * time.Second, "ten"},
+		{15 * time.Second, "fifteen seconds left"},
+		{30 * time.Second, "thirty seconds left"},
+		{time.Minute, "one minute left"},
+		{5 * time.Minute, "five minutes left"},
+		{90 * time.Second, "one minute thirty seconds left"},
+		{25*time.Minute + time.Second, "twenty five minutes one second left"},
+		{time.Hour, "one hour left"},
+		{2*time.Hour + 45*time.Minute + 10*time.Second, "two hours forty five minutes left"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remaining.String(), func(t *testing.T) {
+			result := strings.Join(spokenTime(tt.remaining), " ")
+			if result != tt.expected {
+				t.Errorf("spokenTime(%v) = %q, want %q", tt.remaining, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestVoicePack tests loading clips and assembling phrases
+func TestVoicePack(t *testing.T) {
+	fsys := fstest.MapFS{
+		"five.wav":    {Data: wavFile(1, 1000, 5)},
+		"Minutes.WAV": {Data: wavFile(1, 1000, 6)},
+		"README.adoc": {Data: []byte("not a clip")},
+	}
+	pack, err := loadVoicePack(fsys, 1000)
+	if err != nil {
+		t.Fatalf("loadVoicePack() error = %v", err)
+	}
+	if len(pack) != 2 || pack["five"] == nil || pack["minutes"] == nil {
+		t.Fatalf("loadVoicePack() words = %v, want five and minutes", pack)
+	}
+
+	if missing := pack.Missing(spokenTime(5 * time.Minute)); !slices.Equal(missing, []string{"left"}) {
+		t.Errorf("Missing() = %v, want [left]", missing)
+	}
+	if _, err := pack.Phrase([]string{"five", "left"}, 1000); err == nil || err.Error() != "no voice clip for left" {
+		t.Errorf("Phrase() error = %v, want no voice clip for left", err)
+	}
+
+	pcm, err := pack.Phrase([]string{"five", "minutes"}, 1000)
+	if err != nil {
+		t.Fatalf("Phrase() error = %v", err)
+	}
+	// 60 frames of silence at 1000 Hz between the two one-frame clips
+	if len(pcm) != (1+60+1)*4 {
+		t.Fatalf("Phrase() length = %d, want %d", len(pcm), (1+60+1)*4)
+	}
+	if pcm[0] != 5 || pcm[4] != 0 || pcm[len(pcm)-4] != 6 {
+		t.Errorf("Phrase() = % x..., want five, a pause, then minutes", pcm[:8])
+	}
+
+	if _, err := loadVoicePack(fstest.MapFS{"bad.wav": {Data: []byte("RIFF\x04\x00\x00\x00WAVE")}}, 1000); err == nil ||
+		!strings.HasPrefix(err.Error(), "voice clip bad.wav:") {
+		t.Errorf("loadVoicePack() error = %v, want the bad clip named", err)
+	}
+}
+
+// TestBuiltinVoicePack tests that the clips built into bleep cover every word
+// an announcement can use
+func TestBuiltinVoicePack(t *testing.T) {
+	voiceFS, err := fs.Sub(voiceFiles, "voice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack, err := loadVoicePack(voiceFS, 48000)
+	if err != nil {
+		t.Fatalf("loadVoicePack() error = %v", err)
+	}
+
+	words := []string{"hour", "hours", "minute", "minutes", "second", "seconds", "left", "work", "break"}
+	for n := range 100 {
+		words = append(words, numberWords(n)...)
+	}
+	if missing := pack.Missing(words); len(missing) > 0 {
+		t.Errorf("built-in voice clips missing %v", missing)
+	}
+}
@@ -0,0 +1,713 @@
+//go:build ignore
+
+// voicegen.go synthesizes the voice clips in voice/ with a Klatt-style
+// formant synthesizer: a glottal source and aspiration noise excite a
+// cascade of formant resonators, frication noise a parallel bank. The
+// output is the same on every run.
+//
+//	go run voicegen.go [-o voice] [-w word]
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const sr = 16000.0
+
+type resonator struct{ a, b, c, y1, y2 float64 }
+
+func (r *resonator) set(f, bw float64) {
+	c := -math.Exp(-2 * math.Pi * bw / sr)
+	b := 2 * math.Exp(-math.Pi*bw/sr) * math.Cos(2*math.Pi*f/sr)
+	r.a, r.b, r.c = 1-b-c, b, c
+}
+
+func (r *resonator) step(x float64) float64 {
+	y := r.a*x + r.b*r.y1 + r.c*r.y2
+	r.y2, r.y1 = r.y1, y
+	return y
+}
+
+type antiresonator struct{ a, b, c, x1, x2 float64 }
+
+func (r *antiresonator) set(f, bw float64) {
+	var res resonator
+	res.set(f, bw)
+	r.a, r.b, r.c = 1/res.a, -res.b/res.a, -res.c/res.a
+}
+
+func (r *antiresonator) step(x float64) float64 {
+	y := r.a*x + r.b*r.x1 + r.c*r.x2
+	r.x2, r.x1 = r.x1, x
+	return y
+}
+
+const (
+	pF1 = iota
+	pF2
+	pF3
+	pF4
+	pF5
+	pF6
+	pB1
+	pB2
+	pB3
+	pB4
+	pB5
+	pB6
+	pFNP
+	pFNZ
+	pAV // dB, <= 0 is off
+	pAH
+	pAF
+	pA2
+	pA3
+	pA4
+	pA5
+	pA6
+	pAB
+	nParams
+)
+
+// amplitude parameters interpolate faster than the formants
+func isAmp(i int) bool { return i >= pAV }
+
+type params [nParams]float64
+
+func lerp(a, b params, x float64) params {
+	var p params
+	for i := range p {
+		p[i] = a[i] + (b[i]-a[i])*x
+	}
+	return p
+}
+
+// base returns neutral parameters: no sound, schwa-like formants
+func base() params {
+	var p params
+	copy(p[:], []float64{500, 1500, 2500, 3300, 3750, 4900, 60, 90, 150, 250, 200, 1000, 270, 270})
+	return p
+}
+
+type kind int
+
+const (
+	vowel kind = iota
+	glide
+	nasal
+	fricative
+	stop
+	flap
+)
+
+type phone struct {
+	kind     kind
+	f        [3]float64 // F1-F3
+	fEnd     [3]float64 // diphthong end, zero if none
+	b        [3]float64
+	voiced   bool
+	af       float64    // frication amplitude
+	par      [5]float64 // A2-A6 of the frication or burst
+	ab       float64    // bypass amplitude
+	f6, b6   float64
+	dur      float64 // base duration in ms
+	fnz      float64 // nasal zero
+	locusFor string  // "velar" computes the locus from the vowel
+}
+
+var phones = map[string]phone{
+	"IY": {kind: vowel, f: [3]float64{300, 2200, 2950}, b: [3]float64{45, 150, 300}},
+	"IH": {kind: vowel, f: [3]float64{420, 1850, 2550}, b: [3]float64{50, 100, 140}},
+	"EH": {kind: vowel, f: [3]float64{560, 1720, 2480}, b: [3]float64{60, 90, 200}},
+	"AH": {kind: vowel, f: [3]float64{640, 1200, 2500}, b: [3]float64{80, 60, 140}},
+	"AX": {kind: vowel, f: [3]float64{520, 1420, 2400}, b: [3]float64{80, 80, 160}},
+	"AO": {kind: vowel, f: [3]float64{590, 900, 2500}, b: [3]float64{90, 100, 80}},
+	"ER": {kind: vowel, f: [3]float64{460, 1300, 1600}, b: [3]float64{100, 60, 110}},
+	"UW": {kind: vowel, f: [3]float64{360, 1200, 2250}, fEnd: [3]float64{310, 1000, 2200}, b: [3]float64{65, 110, 140}},
+	"OW": {kind: vowel, f: [3]float64{550, 1100, 2400}, fEnd: [3]float64{430, 850, 2350}, b: [3]float64{80, 70, 70}},
+	"AY": {kind: vowel, f: [3]float64{700, 1220, 2550}, fEnd: [3]float64{420, 1950, 2550}, b: [3]float64{100, 70, 200}},
+	"EY": {kind: vowel, f: [3]float64{500, 1800, 2500}, fEnd: [3]float64{340, 2150, 2700}, b: [3]float64{70, 100, 200}},
+	"AW": {kind: vowel, f: [3]float64{700, 1250, 2550}, fEnd: [3]float64{440, 950, 2350}, b: [3]float64{80, 70, 140}},
+
+	"W": {kind: glide, f: [3]float64{290, 650, 2150}, b: [3]float64{50, 80, 60}, dur: 65},
+	"R": {kind: glide, f: [3]float64{330, 1100, 1450}, b: [3]float64{70, 100, 120}, dur: 60},
+	"L": {kind: glide, f: [3]float64{330, 1050, 2800}, b: [3]float64{50, 100, 280}, dur: 65},
+
+	"M": {kind: nasal, f: [3]float64{300, 1100, 2150}, b: [3]float64{40, 200, 200}, dur: 75, fnz: 1100},
+	"N": {kind: nasal, f: [3]float64{300, 1500, 2500}, b: [3]float64{40, 300, 300}, dur: 70, fnz: 1700},
+
+	"F":  {kind: fricative, f: [3]float64{340, 1100, 2080}, b: [3]float64{200, 120, 150}, af: 60, ab: 80, dur: 110},
+	"V":  {kind: fricative, f: [3]float64{250, 1100, 2080}, b: [3]float64{60, 90, 120}, voiced: true, af: 50, ab: 68, dur: 70},
+	"TH": {kind: fricative, f: [3]float64{320, 1400, 2540}, b: [3]float64{200, 90, 200}, af: 58, par: [5]float64{0, 0, 0, 0, 52}, ab: 68, f6: 5000, b6: 1500, dur: 110},
+	"S":  {kind: fricative, f: [3]float64{320, 1500, 2600}, b: [3]float64{200, 80, 200}, af: 60, par: [5]float64{0, 0, 0, 40, 60}, f6: 5400, b6: 900, dur: 120},
+	"Z":  {kind: fricative, f: [3]float64{250, 1500, 2600}, b: [3]float64{70, 60, 180}, voiced: true, af: 54, par: [5]float64{0, 0, 0, 38, 58}, f6: 5400, b6: 900, dur: 90},
+
+	"T":  {kind: stop, f: [3]float64{220, 1750, 2650}, b: [3]float64{70, 100, 170}, af: 60, par: [5]float64{0, 0, 42, 52, 58}, f6: 5000, b6: 1200},
+	"D":  {kind: stop, f: [3]float64{200, 1700, 2600}, b: [3]float64{70, 100, 170}, voiced: true, af: 56, par: [5]float64{0, 0, 40, 48, 52}, f6: 5000, b6: 1200},
+	"K":  {kind: stop, f: [3]float64{260, 2000, 2300}, b: [3]float64{90, 110, 150}, af: 60, par: [5]float64{62, 70, 62, 0, 0}, locusFor: "velar"},
+	"B":  {kind: stop, f: [3]float64{220, 850, 2100}, b: [3]float64{65, 90, 125}, voiced: true, af: 54, ab: 74},
+	"DX": {kind: flap, f: [3]float64{280, 1700, 2600}, b: [3]float64{70, 100, 170}, voiced: true},
+}
+
+func isDiphthong(ph phone) bool { return ph.fEnd != [3]float64{} }
+
+func voiceless(name string) bool {
+	switch name {
+	case "T", "K", "S", "F", "TH":
+		return true
+	}
+	return false
+}
+
+type segment struct {
+	name       string
+	dur        float64 // seconds
+	start, end params
+	fw, aw     float64 // transition widths into the segment
+}
+
+func formants(p *params, f, b [3]float64) {
+	p[pF1], p[pF2], p[pF3] = f[0], f[1], f[2]
+	p[pB1], p[pB2], p[pB3] = b[0], b[1], b[2]
+}
+
+type token struct {
+	name   string
+	stress int // vowels only: 0, 1 or 2
+}
+
+// parse splits a pronunciation into phones and vowel stress
+func parse(pron string) []token {
+	var toks []token
+	for _, s := range strings.Fields(pron) {
+		t := token{name: s, stress: -1}
+		if c := s[len(s)-1]; c >= '0' && c <= '2' {
+			t.name, t.stress = s[:len(s)-1], int(c-'0')
+		}
+		if _, ok := phones[t.name]; !ok {
+			panic("unknown phone " + s)
+		}
+		toks = append(toks, t)
+	}
+	return toks
+}
+
+// vowelNear returns the formants of the closest vowel to token i
+func vowelNear(toks []token, i int) [3]float64 {
+	for d := 1; d < len(toks); d++ {
+		for _, j := range []int{i + d, i - d} {
+			if j >= 0 && j < len(toks) && phones[toks[j].name].kind == vowel {
+				ph := phones[toks[j].name]
+				if j < i && isDiphthong(ph) {
+					return ph.fEnd
+				}
+				return ph.f
+			}
+		}
+	}
+	return [3]float64{500, 1500, 2500}
+}
+
+// build turns a pronunciation into segments with their parameters
+func build(pron string) []segment {
+	toks := parse(pron)
+	var segs []segment
+	add := func(name string, ms float64, start, end params, fw, aw float64) {
+		segs = append(segs, segment{name: name, dur: ms / 1000, start: start, end: end, fw: fw, aw: aw})
+	}
+	lastVowel := -1
+	for i, t := range toks {
+		if phones[t.name].kind == vowel {
+			lastVowel = i
+		}
+	}
+
+	for i, t := range toks {
+		ph := phones[t.name]
+		final := i == len(toks)-1
+		next := token{stress: -1}
+		if !final {
+			next = toks[i+1]
+		}
+		prev := token{stress: -1}
+		if i > 0 {
+			prev = toks[i-1]
+		}
+		p := base()
+		formants(&p, ph.f, ph.b)
+
+		switch ph.kind {
+		case vowel:
+			ms := map[int]float64{0: 75, 1: 165, 2: 135}[t.stress]
+			av := map[int]float64{0: 56, 1: 60, 2: 59}[t.stress]
+			if isDiphthong(ph) {
+				ms *= 1.3
+			}
+			if i == lastVowel {
+				ms *= 1.35 // the end of the word is drawn out
+			}
+			if !final && voiceless(next.name) {
+				ms *= 0.8
+			}
+			p[pAV] = av
+			end := p
+			if isDiphthong(ph) {
+				formants(&end, ph.fEnd, ph.b)
+			}
+			add(t.name, ms, p, end, 0.05, 0.015)
+
+		case glide:
+			ms := ph.dur
+			p[pAV] = 54
+			if t.name == "L" && final {
+				ms = 80
+			}
+			if voiceless(prev.name) && phones[prev.name].kind == stop {
+				// devoiced after an aspirated stop: aspiration goes on
+				// through the glide
+				p[pAV] = 40
+				p[pAH] = 52
+			}
+			add(t.name, ms, p, p, 0.045, 0.02)
+
+		case nasal:
+			ms := ph.dur
+			if final {
+				ms *= 1.6
+			}
+			p[pAV] = 55
+			p[pFNP], p[pFNZ] = 270, ph.fnz
+			p[pF1], p[pB1] = 250, 60
+			add(t.name, ms, p, p, 0.03, 0.02)
+
+		case fricative:
+			ms := ph.dur
+			if final {
+				ms *= 1.35
+			}
+			p[pAF] = ph.af
+			for k, a := range ph.par {
+				p[pA2+k] = a
+			}
+			p[pAB] = ph.ab
+			if ph.f6 > 0 {
+				p[pF6], p[pB6] = ph.f6, ph.b6
+			}
+			end := p
+			if ph.voiced {
+				p[pAV] = 47
+				end[pAV] = 47
+				if final {
+					// final voiced fricatives devoice
+					end[pAV] = 0
+					end[pAF] -= 4
+				}
+			}
+			add(t.name, ms, p, end, 0.04, 0.02)
+
+		case flap:
+			p[pAV] = 48
+			add(t.name, 28, p, p, 0.03, 0.012)
+
+		case stop:
+			if ph.locusFor == "velar" {
+				v := vowelNear(toks, i)
+				f2 := math.Min(math.Max(v[1]+150, 1400), 2250)
+				formants(&p, [3]float64{260, f2, f2 + 350}, ph.b)
+			}
+			closure := p
+			initial := i == 0
+			cms := 60.0
+			switch {
+			case initial:
+				cms = 25
+			case prev.name == "N":
+				cms = 35
+			case final:
+				cms = 70
+			}
+			if ph.voiced {
+				closure[pAV] = 38
+				closure[pF1], closure[pB1] = 180, 60
+				if initial {
+					closure[pAV] = 0
+				}
+			}
+			add(t.name+"-closure", cms, closure, closure, 0.05, 0.015)
+
+			burst := p
+			burst[pAF] = ph.af
+			if final {
+				burst[pAF] -= 8
+			}
+			for k, a := range ph.par {
+				burst[pA2+k] = a
+			}
+			burst[pAB] = ph.ab
+			if ph.f6 > 0 {
+				burst[pF6], burst[pB6] = ph.f6, ph.b6
+			}
+			bms := 10.0
+			if t.name == "K" {
+				bms = 16
+			}
+			if ph.voiced {
+				bms = 6
+				burst[pAV] = 45
+			}
+			add(t.name+"-burst", bms, burst, burst, 0.001, 0.002)
+
+			if ph.voiced {
+				continue
+			}
+			asp := 0.0
+			switch {
+			case prev.name == "S":
+				asp = 8
+			case next.stress == 1 || next.stress == 2:
+				asp = 55
+			case next.name == "W" || next.name == "R":
+				asp = 35
+			case final:
+				asp = 30
+			default:
+				asp = 22
+			}
+			a := p
+			a[pAH] = 58
+			if final {
+				a[pAH] = 50
+			}
+			end := a
+			if final {
+				end[pAH] = 30
+			}
+			add(t.name+"-asp", asp, a, end, 0.04, 0.004)
+		}
+	}
+	return segs
+}
+
+type boundary struct{ fw, aw float64 }
+
+// at returns the parameters at time t
+func at(segs []segment, starts []float64, bounds []boundary, t float64) params {
+	k := sort.Search(len(segs), func(i int) bool { return starts[i]+segs[i].dur > t })
+	if k == len(segs) {
+		k = len(segs) - 1
+	}
+	within := func(i int) params {
+		s := segs[i]
+		x := (t - starts[i]) / s.dur
+		x = math.Max(0, math.Min(1, (x-0.15)/0.7))
+		x = x * x * (3 - 2*x)
+		return lerp(s.start, s.end, x)
+	}
+	cur := within(k)
+	out := cur
+	blend := func(other params, a float64, amp bool) {
+		for i := range out {
+			if isAmp(i) != amp {
+				continue
+			}
+			out[i] = cur[i] + (other[i]-cur[i])*a
+		}
+	}
+	// boundary with the previous segment
+	if k > 0 {
+		b := bounds[k]
+		d := t - starts[k]
+		prev := within(k - 1)
+		for _, amp := range []bool{false, true} {
+			w := b.fw
+			if amp {
+				w = b.aw
+			}
+			if d < w/2 {
+				a := 0.5 - d/w // weight of the previous segment
+				blend(prev, a, amp)
+			}
+		}
+	}
+	if k < len(segs)-1 {
+		b := bounds[k+1]
+		d := starts[k+1] - t
+		next := within(k + 1)
+		for _, amp := range []bool{false, true} {
+			w := b.fw
+			if amp {
+				w = b.aw
+			}
+			if d < w/2 {
+				a := 0.5 - d/w
+				blend(next, a, amp)
+			}
+		}
+	}
+	return out
+}
+
+// db converts a level in dB, 60 being unity gain, to a gain; 0 is off
+func db(x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	return math.Pow(10, (x-60)/20)
+}
+
+// gains of the sources, calibrated so a stressed vowel is 0 dB and an s
+// about 10 dB below
+var (
+	gainV = 1.0
+	gainH = 1.0
+	gainF = 0.03
+)
+
+// render synthesizes the segments at the sample rate sr
+func render(segs []segment, rng *rand.Rand) []float64 {
+	starts := make([]float64, len(segs))
+	total := 0.0
+	for i, s := range segs {
+		starts[i] = total
+		total += s.dur
+	}
+	bounds := make([]boundary, len(segs))
+	for i := 1; i < len(segs); i++ {
+		lim := math.Min(segs[i-1].dur, segs[i].dur)
+		bounds[i] = boundary{math.Min(segs[i].fw, lim), math.Min(segs[i].aw, lim)}
+	}
+
+	pad := 0.03
+	n := int((total + 2*pad) * sr)
+	out := make([]float64, n)
+
+	var casc [5]resonator
+	var par [6]resonator
+	var rnp resonator
+	var rnz antiresonator
+	var tilt, hlp float64
+	phase := 1.0 // position in the glottal period, 0-1; starts a period
+	var t0, prevNoise float64
+	f0jitter := 0.0
+	var p params
+
+	for i := 0; i < n; i++ {
+		t := float64(i)/sr - pad
+		if i%16 == 0 {
+			tc := math.Max(0, math.Min(total-1e-6, t))
+			p = at(segs, starts, bounds, tc)
+			if t < 0 || t > total {
+				p[pAV], p[pAH], p[pAF] = 0, 0, 0
+			}
+			for k := 0; k < 5; k++ {
+				casc[k].set(p[pF1+k], p[pB1+k])
+			}
+			for k := 0; k < 6; k++ {
+				par[k].set(p[pF1+k], p[pB1+k])
+			}
+			rnp.set(p[pFNP], 100)
+			rnz.set(p[pFNZ], 100)
+		}
+
+		// voicing source: KLGLOTT88 flow derivative
+		if phase >= 1 {
+			phase -= 1
+			x := math.Max(0, math.Min(1, t/total))
+			f0 := 112 + 22*math.Sin(math.Pi*math.Min(x/0.25, 1)/2) - 34*math.Pow(x, 1.3)
+			f0jitter = 0.7*f0jitter + 0.3*(rng.Float64()-0.5)*0.02
+			t0 = f0 * (1 + f0jitter)
+		}
+		const oq = 0.6
+		var g float64
+		if phase < oq {
+			x := phase / oq
+			g = (2*x - 3*x*x) * 3
+		}
+		phase += t0 / sr
+		tilt = 0.25*tilt + 0.75*g
+		voice := tilt * db(p[pAV]) * gainV
+
+		noise := rng.Float64()*2 - 1
+		hlp = 0.5*hlp + 0.5*noise
+		asp := hlp * db(p[pAH]) * gainH
+		if p[pAV] > 0 && phase >= oq {
+			asp *= 0.5
+		}
+
+		// cascade
+		x := voice + asp
+		x = rnz.step(rnp.step(x))
+		for k := 4; k >= 0; k-- {
+			x = casc[k].step(x)
+		}
+
+		// parallel: differenced frication noise
+		fr := (noise - prevNoise) * db(p[pAF]) * gainF
+		prevNoise = noise
+		var y float64
+		if p[pAB] > 0 {
+			y = fr * db(p[pAB])
+		}
+		sign := 1.0
+		for k := 1; k < 6; k++ {
+			if a := p[pA2+k-1]; a > 0 {
+				y += sign * par[k].step(fr) * db(a) * float64(k)
+			} else {
+				par[k].step(fr)
+			}
+			sign = -sign
+		}
+		out[i] = x + y
+	}
+	return out
+}
+
+// trim cuts the silence at both ends and fades in and out
+func trim(s []float64) []float64 {
+	peak := 0.0
+	for _, v := range s {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	th := peak * math.Pow(10, -45.0/20)
+	first, last := 0, len(s)-1
+	for first < last && math.Abs(s[first]) < th {
+		first++
+	}
+	for last > first && math.Abs(s[last]) < th {
+		last--
+	}
+	first = max(0, first-int(0.005*sr))
+	last = min(len(s)-1, last+int(0.005*sr))
+	s = s[first : last+1]
+	fade := int(0.004 * sr)
+	for i := 0; i < fade && i < len(s); i++ {
+		g := float64(i) / float64(fade)
+		s[i] *= g
+		s[len(s)-1-i] *= g
+	}
+	return s
+}
+
+// loudness returns the RMS of the loudest 50 ms
+func loudness(s []float64) float64 {
+	w := int(0.05 * sr)
+	best, sum := 0.0, 0.0
+	for i, v := range s {
+		sum += v * v
+		if i >= w {
+			sum -= s[i-w] * s[i-w]
+		}
+		best = math.Max(best, sum)
+	}
+	return math.Sqrt(best / float64(w))
+}
+
+// writeWAV writes 16-bit mono PCM
+func writeWAV(path string, s []float64) error {
+	data := make([]byte, 44+len(s)*2)
+	copy(data, "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(36+len(s)*2))
+	copy(data[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(data[16:], 16)
+	binary.LittleEndian.PutUint16(data[20:], 1)
+	binary.LittleEndian.PutUint16(data[22:], 1)
+	binary.LittleEndian.PutUint32(data[24:], uint32(sr))
+	binary.LittleEndian.PutUint32(data[28:], uint32(sr*2))
+	binary.LittleEndian.PutUint16(data[32:], 2)
+	binary.LittleEndian.PutUint16(data[34:], 16)
+	copy(data[36:], "data")
+	binary.LittleEndian.PutUint32(data[40:], uint32(len(s)*2))
+	for i, v := range s {
+		v = math.Max(-1, math.Min(1, v))
+		binary.LittleEndian.PutUint16(data[44+i*2:], uint16(int16(math.Round(v*32767))))
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// words holds the pronunciations of the clips in ARPAbet
+var words = map[string]string{
+	"zero":      "Z IY1 R OW0",
+	"one":       "W AH1 N",
+	"two":       "T UW1",
+	"three":     "TH R IY1",
+	"four":      "F AO1 R",
+	"five":      "F AY1 V",
+	"six":       "S IH1 K S",
+	"seven":     "S EH1 V AX0 N",
+	"eight":     "EY1 T",
+	"nine":      "N AY1 N",
+	"ten":       "T EH1 N",
+	"eleven":    "IH0 L EH1 V AX0 N",
+	"twelve":    "T W EH1 L V",
+	"thirteen":  "TH ER2 T IY1 N",
+	"fourteen":  "F AO2 R T IY1 N",
+	"fifteen":   "F IH2 F T IY1 N",
+	"sixteen":   "S IH2 K S T IY1 N",
+	"seventeen": "S EH2 V AX0 N T IY1 N",
+	"eighteen":  "EY2 T IY1 N",
+	"nineteen":  "N AY2 N T IY1 N",
+	"twenty":    "T W EH1 N T IY0",
+	"thirty":    "TH ER1 DX IY0",
+	"forty":     "F AO1 R DX IY0",
+	"fifty":     "F IH1 F T IY0",
+	"sixty":     "S IH1 K S T IY0",
+	"seventy":   "S EH1 V AX0 N T IY0",
+	"eighty":    "EY1 DX IY0",
+	"ninety":    "N AY1 N DX IY0",
+	"hour":      "AW1 ER0",
+	"hours":     "AW1 ER0 Z",
+	"minute":    "M IH1 N IH0 T",
+	"minutes":   "M IH1 N IH0 T S",
+	"second":    "S EH1 K AX0 N D",
+	"seconds":   "S EH1 K AX0 N D Z",
+	"left":      "L EH1 F T",
+	"work":      "W ER1 K",
+	"break":     "B R EY1 K",
+}
+
+func main() {
+	dir := flag.String("o", "voice", "output directory")
+	only := flag.String("w", "", "only generate this word")
+	flag.Parse()
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	names := make([]string, 0, len(words))
+	for w := range words {
+		names = append(names, w)
+	}
+	sort.Strings(names)
+	for _, w := range names {
+		if *only != "" && w != *only {
+			continue
+		}
+		rng := rand.New(rand.NewSource(int64(len(w)) * 7919))
+		s := trim(render(build(words[w]), rng))
+		// Normalize the loudest 50 ms, keeping the peak below full scale
+		g := 0.25 / loudness(s)
+		peak := 0.0
+		for i := range s {
+			s[i] *= g
+			peak = math.Max(peak, math.Abs(s[i]))
+		}
+		if peak > 0.95 {
+			for i := range s {
+				s[i] *= 0.95 / peak
+			}
+		}
+		if err := writeWAV(filepath.Join(*dir, w+".wav"), s); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%-10s %5.0f ms\n", w, float64(len(s))/sr*1000)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// warnTolerance is how much further than the time between two checks the
+// countdown may have moved for a warning or tick to still count as reached. Larger
+// jumps come from skips, resets or a suspend, and don't warn.
+const warnTolerance = 500 * time.Millisecond
+
+// parseOffsets parses the times before the beep given to -warn or
+// -announce: comma-separated, e.g. "1m,10s". Groups separated by ";" apply
+// to one interval each, e.g. "2m;30s" with -m 25,5; an empty group means
+// none for the interval. Plain numbers are seconds.
+func parseOffsets(name, s string) ([][]time.Duration, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var groups [][]time.Duration
+	for _, group := range strings.Split(s, ";") {
+		var offsets []time.Duration
+		for _, part := range strings.Split(group, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			offset, err := time.ParseDuration(part)
+			if err != nil {
+				seconds, convErr := strconv.Atoi(part)
+				if convErr != nil {
+					return nil, fmt.Errorf("invalid -%s time %q (e.g. 1m, 30s or 10)", name, part)
+				}
+				offset = time.Duration(seconds) * time.Second
+			}
+			if offset <= 0 {
+				return nil, fmt.Errorf("-%s time %q must be positive", name, part)
+			}
+			offsets = append(offsets, offset)
+		}
+		// Longest first, the order in which the countdown reaches them
+		slices.Sort(offsets)
+		slices.Reverse(offsets)
+		groups = append(groups, slices.Compact(offsets))
+	}
+	return groups, nil
+}
+
+// checkOffsets reports -warn or -announce groups that don't fit the
+// intervals: more groups than intervals, or a time that is not shorter than
+// its interval
+func checkOffsets(name string, groups [][]time.Duration, intervals []time.Duration) error {
+	if len(groups) > 1 && len(groups) > len(intervals) {
+		return fmt.Errorf("-%s has %d groups but there are only %d intervals", name, len(groups), len(intervals))
+	}
+	w := NewWarner(groups)
+	for i, interval := range intervals {
+		if offsets := w.For(i); len(offsets) > 0 && offsets[0] >= interval {
+			return fmt.Errorf("-%s time %s is not shorter than interval %d (%s)", name, formatDuration(offsets[0]), i+1, formatDuration(interval))
+		}
+	}
+	return nil
+}
+
+// countdownCheck is the countdown seen by one check
+type countdownCheck struct {
+	segment   int
+	remaining time.Duration
+	at        time.Time
+}
+
+// countedTo reports whether the countdown moved on normally from c to next,
+// rather than jumping through a skip, reset or suspend
+func (c countdownCheck) countedTo(next countdownCheck) bool {
+	counted := c.remaining - next.remaining
+	return !c.at.IsZero() && c.segment == next.segment && counted > 0 && counted <= next.at.Sub(c.at)+warnTolerance
+}
+
+// Warner decides when the warnings or announcements before a beep are due.
+// One is due when the countdown reaches its time while counting down
+// normally.
+type Warner struct {
+	Offsets [][]time.Duration // per interval, longest first; a single group applies to all
+
+	prev countdownCheck
+}
+
+// NewWarner creates a warner for the parsed -warn groups
+func NewWarner(offsets [][]time.Duration) *Warner {
+	return &Warner{Offsets: offsets}
+}
+
+// For returns the warning times of an interval
+func (w *Warner) For(segment int) []time.Duration {
+	if len(w.Offsets) == 1 {
+		return w.Offsets[0]
+	}
+	if segment < len(w.Offsets) {
+		return w.Offsets[segment]
+	}
+	return nil
+}
+
+// Active reports whether the countdown is within the warning period, from
+// the first warning to the beep
+func (w *Warner) Active(segment int, remaining time.Duration) bool {
+	offsets := w.For(segment)
+	return len(offsets) > 0 && remaining <= offsets[0]
+}
+
+// Next returns the time until the next warning of the interval, or false if
+// none is left
+func (w *Warner) Next(segment int, remaining time.Duration) (time.Duration, bool) {
+	for _, offset := range w.For(segment) {
+		if offset < remaining {
+			return remaining - offset, true
+		}
+	}
+	return 0, false
+}
+
+// Check returns the warning reached since the last check, if any. When
+// several were reached at once only the latest is returned.
+func (w *Warner) Check(segment int, remaining time.Duration, now time.Time) (time.Duration, bool) {
+	prev := w.prev
+	w.prev = countdownCheck{segment, remaining, now}
+	if !prev.countedTo(w.prev) {
+		return 0, false
+	}
+	var reached time.Duration
+	for _, offset := range w.For(segment) {
+		if remaining <= offset && offset < prev.remaining {
+			reached = offset
+		}
+	}
+	return reached, reached > 0
+}
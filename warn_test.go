@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestParseOffsets tests the parseOffsets function
+func TestParseOffsets(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    [][]time.Duration
+		expectError bool
+	}{
+		{"", nil, false},
+		{"1m", [][]time.Duration{{time.Minute}}, false},
+		{"10s,1m", [][]time.Duration{{time.Minute, 10 * time.Second}}, false},
+		{"30, 30s", [][]time.Duration{{30 * time.Second}}, false},
+		{"2m;1m,10s", [][]time.Duration{{2 * time.Minute}, {time.Minute, 10 * time.Second}}, false},
+		{";30s", [][]time.Duration{nil, {30 * time.Second}}, false},
+		{"soon", nil, true},
+		{"0s", nil, true},
+		{"-1m", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			result, err := parseOffsets("warn", tt.value)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("parseOffsets(%q) expected error, got nil", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseOffsets(%q) unexpected error: %v", tt.value, err)
+			}
+			if !slices.EqualFunc(result, tt.expected, slices.Equal) {
+				t.Errorf("parseOffsets(%q) = %v, want %v", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestCheckOffsets tests the checkOffsets function
+func TestCheckOffsets(t *testing.T) {
+	intervals := []time.Duration{25 * time.Minute, 5 * time.Minute}
+	tests := []struct {
+		name        string
+		groups      [][]time.Duration
+		expectError bool
+	}{
+		{"fits all", [][]time.Duration{{time.Minute}}, false},
+		{"per interval", [][]time.Duration{{10 * time.Minute}, {time.Minute}}, false},
+		{"too long for the break", [][]time.Duration{{10 * time.Minute}}, true},
+		{"too many groups", [][]time.Duration{{time.Minute}, {time.Minute}, {time.Minute}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOffsets("warn", tt.groups, intervals)
+			if tt.expectError && err == nil {
+				t.Error("checkOffsets() expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("checkOffsets() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestWarner tests when warnings are due
+func TestWarner(t *testing.T) {
+	w := NewWarner([][]time.Duration{{time.Minute, 10 * time.Second}, nil})
+
+	if w.Active(0, 61*time.Second) || !w.Active(0, time.Minute) || w.Active(1, time.Second) {
+		t.Error("Active() reports the wrong warning period")
+	}
+	if next, ok := w.Next(0, 90*time.Second); !ok || next != 30*time.Second {
+		t.Errorf("Next() = %v, %v, want 30s", next, ok)
+	}
+	if next, ok := w.Next(0, 30*time.Second); !ok || next != 20*time.Second {
+		t.Errorf("Next() = %v, %v, want 20s", next, ok)
+	}
+	if _, ok := w.Next(0, 5*time.Second); ok {
+		t.Error("Next() after the last warning should report none")
+	}
+
+	start := time.Now()
+	check := func(segment int, remaining, since time.Duration) (time.Duration, bool) {
+		return w.Check(segment, remaining, start.Add(since))
+	}
+
+	// The first check has nothing to compare with
+	if _, ok := check(0, 61*time.Second, 0); ok {
+		t.Error("first Check() reported a warning")
+	}
+	if offset, ok := check(0, 60*time.Second, time.Second); !ok || offset != time.Minute {
+		t.Errorf("Check() = %v, %v, want the 1m warning", offset, ok)
+	}
+	if _, ok := check(0, 59*time.Second, 2*time.Second); ok {
+		t.Error("Check() reported the 1m warning twice")
+	}
+
+	// Paused: the countdown doesn't move
+	if _, ok := check(0, 59*time.Second, 10*time.Second); ok {
+		t.Error("Check() reported a warning while paused")
+	}
+
+	// Jumps past a warning, e.g. from setting the time, don't warn
+	if _, ok := check(0, 5*time.Second, 11*time.Second); ok {
+		t.Error("Check() reported a warning after a jump")
+	}
+
+	// Neither does starting another interval
+	check(0, 11*time.Second, 12*time.Second)
+	if _, ok := check(1, 10*time.Second, 13*time.Second); ok {
+		t.Error("Check() reported a warning for a new interval")
+	}
+}
+
+// TestFormatWarningOutput tests the warning output and the warning class
+func TestFormatWarningOutput(t *testing.T) {
+	timestamp := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	data := TemplateData{Remaining: time.Minute, Index: 1, Count: 1, State: "warning"}
+	ticking := data
+	ticking.State = "counting"
+	ticking.Warning = true
+
+	tests := []struct {
+		name     string
+		mode     OutputMode
+		data     TemplateData
+		expected string
+	}{
+		{"json", ModeJSON, data, `{"text":"1m 0s","tooltip":"25m 0s","class":"warning","remaining":60}`},
+		{"json tick", ModeJSON, ticking, `{"text":"1m 0s","tooltip":"25m 0s","class":"warning","remaining":60}`},
+		{"tmux", ModeTmux, ticking, "#[fg=#fab387]1m 0s#[default]"},
+		{"verbose", ModeVerbose, data, "\r[10:30:00] Warning: 1m 0s left              \n"},
+		{"default", ModeDefault, data, "WARNING 2025-01-01T10:30:00Z\n"},
+		{"events", ModeEvents, data, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := OutputConfig{Mode: tt.mode, MinutesList: []int{25}, SecondsList: []int{0}, IntervalCount: 1}
+			if result := FormatOutput(config, tt.data, timestamp); result != tt.expected {
+				t.Errorf("FormatOutput() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestGenerateTone tests the generated PCM
+func TestGenerateTone(t *testing.T) {
+	pcm := generateTone(1000, 100*time.Millisecond, 0.5, 48000)
+	if len(pcm) != 4800*4 {
+		t.Fatalf("len = %d, want %d", len(pcm), 4800*4)
+	}
+
+	// Fades in from silence, both channels carry the same sample
+	if first := int16(binary.LittleEndian.Uint16(pcm)); first != 0 {
+		t.Errorf("first sample = %d, want 0", first)
+	}
+	var peak int16
+	for i := 0; i < len(pcm); i += 4 {
+		left := int16(binary.LittleEndian.Uint16(pcm[i:]))
+		if right := int16(binary.LittleEndian.Uint16(pcm[i+2:])); left != right {
+			t.Fatalf("channels differ at sample %d", i/4)
+		}
+		peak = max(peak, left)
+	}
+	if want := int16(16383); peak < want-100 || peak > want {
+		t.Errorf("peak = %d, want about %d", peak, want)
+	}
+}
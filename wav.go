@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// WAV sample formats
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE // the format is in the first two bytes of the subformat GUID
+)
+
+// wavDecoder decodes RIFF WAVE files with integer PCM samples of 8 to 32 bits
+// or float samples of 32 or 64 bits
+type wavDecoder struct{}
+
+func (wavDecoder) Format() string { return "WAV" }
+
+func (wavDecoder) Sniff(header []byte) bool {
+	return len(header) >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WAVE"
+}
+
+func (d wavDecoder) Decode(data []byte) (*Sound, error) {
+	if !d.Sniff(data) {
+		return nil, errors.New("not a WAV file")
+	}
+	var format, channels, bits, rate int
+	var layout []Speaker
+	var samples []byte
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		body := data[pos+8 : min(pos+8+size, len(data))]
+		switch id {
+		case "fmt ":
+			if len(body) < 16 {
+				return nil, errors.New("short fmt chunk")
+			}
+			format = int(binary.LittleEndian.Uint16(body))
+			channels = int(binary.LittleEndian.Uint16(body[2:]))
+			rate = int(binary.LittleEndian.Uint32(body[4:]))
+			bits = int(binary.LittleEndian.Uint16(body[14:]))
+			if format == wavFormatExtensible {
+				if len(body) < 26 {
+					return nil, errors.New("short extensible fmt chunk")
+				}
+				layout = maskLayout(binary.LittleEndian.Uint32(body[20:]), channels)
+				format = int(binary.LittleEndian.Uint16(body[24:]))
+			}
+		case "data":
+			samples = body
+		}
+		// Chunks are padded to an even size
+		pos += 8 + size + size%2
+	}
+
+	if samples == nil || channels == 0 {
+		return nil, errors.New("missing fmt or data chunk")
+	}
+	var decode func([]byte) float32
+	switch {
+	case format == wavFormatPCM && bits == 8:
+		// 8-bit samples are unsigned
+		decode = func(b []byte) float32 { return intSample(int64(b[0])-128, 8) }
+	case format == wavFormatPCM && bits == 16:
+		decode = func(b []byte) float32 { return intSample(int64(int16(binary.LittleEndian.Uint16(b))), 16) }
+	case format == wavFormatPCM && bits == 24:
+		decode = func(b []byte) float32 {
+			return intSample(int64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8), 24)
+		}
+	case format == wavFormatPCM && bits == 32:
+		decode = func(b []byte) float32 { return intSample(int64(int32(binary.LittleEndian.Uint32(b))), 32) }
+	case format == wavFormatFloat && bits == 32:
+		decode = func(b []byte) float32 { return math.Float32frombits(binary.LittleEndian.Uint32(b)) }
+	case format == wavFormatFloat && bits == 64:
+		decode = func(b []byte) float32 { return float32(math.Float64frombits(binary.LittleEndian.Uint64(b))) }
+	case format == wavFormatPCM || format == wavFormatFloat:
+		return nil, fmt.Errorf("unsupported %d-bit samples", bits)
+	default:
+		return nil, fmt.Errorf("unsupported format %d, want PCM or float", format)
+	}
+
+	size := bits / 8
+	sound := &Sound{Samples: make([]float32, len(samples)/size), SampleRate: rate, Channels: channels, Layout: layout}
+	for i := range sound.Samples {
+		sound.Samples[i] = decode(samples[i*size:])
+	}
+	return sound, nil
+}
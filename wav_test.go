@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"slices"
+	"testing"
+)
+
+// wavData builds a WAV file from raw sample data; extensible formats use
+// WAVE_FORMAT_EXTENSIBLE with format as the subformat
+func wavData(format, channels, sampleRate, bits int, extensible bool, data []byte) []byte {
+	var fmtChunk bytes.Buffer
+	tag := format
+	if extensible {
+		tag = wavFormatExtensible
+	}
+	for _, v := range []any{
+		uint16(tag), uint16(channels), uint32(sampleRate),
+		uint32(sampleRate * channels * bits / 8), uint16(channels * bits / 8), uint16(bits),
+	} {
+		binary.Write(&fmtChunk, binary.LittleEndian, v)
+	}
+	if extensible {
+		// Extension size, valid bits, channel mask, then the subformat GUID
+		for _, v := range []any{uint16(22), uint16(bits), uint32(0)} {
+			binary.Write(&fmtChunk, binary.LittleEndian, v)
+		}
+		guid := make([]byte, 16)
+		binary.LittleEndian.PutUint16(guid, uint16(format))
+		fmtChunk.Write(guid)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(4+8+fmtChunk.Len()+8+len(data)))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(fmtChunk.Len()))
+	buf.Write(fmtChunk.Bytes())
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// wavFile builds a 16-bit PCM WAV file from samples
+func wavFile(channels, sampleRate int, samples ...int16) []byte {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, samples)
+	return wavData(wavFormatPCM, channels, sampleRate, 16, false, data.Bytes())
+}
+
+// le encodes values little-endian
+func le(values ...any) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	return buf.Bytes()
+}
+
+// TestWAVDecoder tests decoding the WAV sample formats
+func TestWAVDecoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		channels int
+		expected []float32
+		wantErr  string
+	}{
+		{
+			name:     "8-bit unsigned",
+			data:     wavData(wavFormatPCM, 1, 8000, 8, false, []byte{0, 128, 192}),
+			channels: 1,
+			expected: []float32{-1, 0, 0.5},
+		},
+		{
+			name:     "16-bit stereo",
+			data:     wavFile(2, 8000, -32768, 16384, 0, -8192),
+			channels: 2,
+			expected: []float32{-1, 0.5, 0, -0.25},
+		},
+		{
+			name:     "24-bit",
+			data:     wavData(wavFormatPCM, 1, 8000, 24, false, []byte{0, 0, 0xC0, 0, 0, 0x40}),
+			channels: 1,
+			expected: []float32{-0.5, 0.5},
+		},
+		{
+			name:     "32-bit",
+			data:     wavData(wavFormatPCM, 1, 8000, 32, false, le(int32(math.MinInt32), int32(1<<29))),
+			channels: 1,
+			expected: []float32{-1, 0.25},
+		},
+		{
+			name:     "32-bit float",
+			data:     wavData(wavFormatFloat, 1, 8000, 32, false, le(float32(0.75), float32(-0.125))),
+			channels: 1,
+			expected: []float32{0.75, -0.125},
+		},
+		{
+			name:     "64-bit float",
+			data:     wavData(wavFormatFloat, 1, 8000, 64, false, le(0.75, -0.125)),
+			channels: 1,
+			expected: []float32{0.75, -0.125},
+		},
+		{
+			name:     "extensible",
+			data:     wavData(wavFormatFloat, 1, 8000, 32, true, le(float32(0.5))),
+			channels: 1,
+			expected: []float32{0.5},
+		},
+		{
+			name:    "unsupported bits",
+			data:    wavData(wavFormatPCM, 1, 8000, 12, false, []byte{0, 0}),
+			wantErr: "unsupported 12-bit samples",
+		},
+		{
+			name:    "unsupported format",
+			data:    wavData(2, 1, 8000, 4, false, []byte{0}),
+			wantErr: "unsupported format 2, want PCM or float",
+		},
+		{
+			name:    "no data chunk",
+			data:    wavFile(1, 8000)[:36],
+			wantErr: "missing fmt or data chunk",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sound, err := wavDecoder{}.Decode(tt.data)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Decode() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if sound.SampleRate != 8000 || sound.Channels != tt.channels {
+				t.Errorf("Decode() format = %d Hz, %d channels, want 8000 Hz, %d channels", sound.SampleRate, sound.Channels, tt.channels)
+			}
+			if !slices.Equal(sound.Samples, tt.expected) {
+				t.Errorf("Decode() = %v, want %v", sound.Samples, tt.expected)
+			}
+		})
+	}
+}
+
+// TestWAVFile tests decoding files from other encoders: the same pluck as
+// 16-bit PCM and as 24-bit WAVE_FORMAT_EXTENSIBLE. The sums cover every
+// sample.
+func TestWAVFile(t *testing.T) {
+	tests := []struct {
+		file  string
+		bits  int
+		first []int64
+		last  []int64
+		sum   int64
+	}{
+		{"testdata/pluck16.wav", 16, []int64{558, -22, 19292, 249}, []int64{-817, 19, 3, -2}, -463547},
+		{"testdata/pluck24.wav", 24, []int64{142693, -5219, 4938255, 64084}, []int64{-210086, 5850, 0, 0}, -118668009},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sound, err := DecodeSound(data)
+			if err != nil {
+				t.Fatalf("DecodeSound() error = %v", err)
+			}
+			if sound.SampleRate != 11025 || sound.Channels != 2 || sound.Frames() != 3307 {
+				t.Fatalf("DecodeSound() = %d Hz, %d channels, %d frames, want 11025 Hz, 2 channels, 3307 frames",
+					sound.SampleRate, sound.Channels, sound.Frames())
+			}
+			samples := make([]int64, len(sound.Samples))
+			var sum int64
+			for i, s := range sound.Samples {
+				samples[i] = int64(math.Round(float64(s) * float64(int64(1)<<(tt.bits-1))))
+				sum += samples[i]
+			}
+			if first := samples[:len(tt.first)]; !slices.Equal(first, tt.first) {
+				t.Errorf("first samples = %v, want %v", first, tt.first)
+			}
+			if last := samples[len(samples)-len(tt.last):]; !slices.Equal(last, tt.last) {
+				t.Errorf("last samples = %v, want %v", last, tt.last)
+			}
+			if sum != tt.sum {
+				t.Errorf("sum of samples = %d, want %d", sum, tt.sum)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// webhookQueueSize bounds the number of events waiting to be delivered
+	// to a single URL; further events are dropped until the queue drains
+	webhookQueueSize = 32
+	// webhookAttempts is the number of delivery attempts per event
+	webhookAttempts = 4
+	// webhookBackoff is the delay before the first retry, doubled each time
+	webhookBackoff = time.Second
+	// webhookTimeout limits a single delivery attempt
+	webhookTimeout = 10 * time.Second
+	// webhookCloseTimeout is how long queued events may take to be delivered
+	// when bleep exits, after which requests in flight are cancelled and the
+	// rest of the queue is dropped
+	webhookCloseTimeout = 5 * time.Second
+)
+
+// webhookEvents are the event types posted to webhooks
+var webhookEvents = map[EventType]bool{
+	EventBeep:         true,
+	EventSegmentStart: true,
+	EventWarning:      true,
+}
+
+// stringList is a flag.Value collecting a flag given multiple times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// webhookFuncs are the helper functions available to -webhook-template, on
+// top of the -format helpers
+var webhookFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. to quote a label inside a JSON body
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	// secs converts a number of seconds into a duration for the -format helpers
+	"secs": secondsDuration,
+}
+
+// parseWebhookTemplate parses a -webhook-template body template
+func parseWebhookTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("webhook").Funcs(templateFuncs).Funcs(webhookFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, Event{Type: EventBeep, Segment: 1, Segments: 1}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// Webhook posts events to a URL from a background worker. Deliveries are
+// retried with exponential backoff; when the queue is full new events are
+// dropped so a dead endpoint never blocks the timer.
+type Webhook struct {
+	URL      string
+	Template *template.Template // optional body template, JSON event otherwise
+	Client   *http.Client
+	Attempts int
+	Backoff  time.Duration
+	OnError  func(err error)
+	queue    chan Event
+	done     chan struct{}
+	ctx      context.Context // cancelled when Close times out
+	cancel   context.CancelFunc
+}
+
+// NewWebhook creates a webhook for url and starts its delivery worker
+func NewWebhook(url string, tmpl *template.Template) *Webhook {
+	w := &Webhook{
+		URL:      url,
+		Template: tmpl,
+		Client:   &http.Client{Timeout: webhookTimeout},
+		Attempts: webhookAttempts,
+		Backoff:  webhookBackoff,
+		OnError: func(err error) {
+			fmt.Fprintf(os.Stderr, "Error sending webhook: %v\n", err)
+		},
+		queue: make(chan Event, webhookQueueSize),
+		done:  make(chan struct{}),
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	go w.worker()
+	return w
+}
+
+// Send queues the event for delivery if it is one of the webhook events
+func (w *Webhook) Send(event Event) {
+	if !webhookEvents[event.Type] {
+		return
+	}
+	select {
+	case w.queue <- event:
+	default:
+		w.OnError(fmt.Errorf("%s: queue full, dropping %s event", w.URL, event.Type))
+	}
+}
+
+// Close stops accepting events and waits until the queued ones have been
+// delivered or the timeout expires. On timeout the request in flight and
+// pending retries are cancelled and the events still queued are dropped.
+func (w *Webhook) Close(timeout time.Duration) {
+	close(w.queue)
+	select {
+	case <-w.done:
+	case <-time.After(timeout):
+		w.cancel()
+		<-w.done
+	}
+	w.cancel()
+}
+
+func (w *Webhook) worker() {
+	defer close(w.done)
+	dropped := 0
+	for event := range w.queue {
+		if w.ctx.Err() != nil {
+			dropped++
+			continue
+		}
+		if err := w.deliver(event); err != nil {
+			w.OnError(fmt.Errorf("%s: %w", w.URL, err))
+		}
+	}
+	if dropped > 0 {
+		w.OnError(fmt.Errorf("%s: shutting down, dropping %s", w.URL, plural(dropped, "queued event")))
+	}
+}
+
+// deliver posts the event, retrying on network errors and retryable status codes
+func (w *Webhook) deliver(event Event) error {
+	body, err := w.body(event)
+	if err != nil {
+		return err
+	}
+
+	backoff := w.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := w.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.Attempts {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-w.ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// body renders the request body for the event
+func (w *Webhook) body(event Event) ([]byte, error) {
+	if w.Template == nil {
+		return json.Marshal(event)
+	}
+	var buf bytes.Buffer
+	if err := w.Template.Execute(&buf, event); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// post sends one request and reports whether a failure is worth retrying
+func (w *Webhook) post(body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bleep/"+version)
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
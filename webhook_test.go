@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// errorCollector records errors reported by a webhook
+type errorCollector struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (c *errorCollector) add(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs = append(c.errs, err)
+}
+
+func (c *errorCollector) list() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]error(nil), c.errs...)
+}
+
+// newTestWebhook creates a webhook with fast retries that collects its errors
+func newTestWebhook(url string) (*Webhook, *errorCollector) {
+	errs := &errorCollector{}
+	w := NewWebhook(url, nil)
+	w.Backoff = time.Millisecond
+	w.OnError = errs.add
+	return w, errs
+}
+
+// TestWebhookPostsEvents tests that beep and segment events are posted as JSON
+func TestWebhookPostsEvents(t *testing.T) {
+	var mu sync.Mutex
+	var received []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decode error: %v", err)
+		}
+		mu.Lock()
+		received = append(received, event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	webhook, errs := newTestWebhook(server.URL)
+	webhook.Send(Event{Version: 1, Type: EventBeep, BeepType: "automatic", Label: "work"})
+	webhook.Send(Event{Version: 1, Type: EventTick})
+	webhook.Send(Event{Version: 1, Type: EventSegmentStart, Label: "break"})
+	webhook.Close(5 * time.Second)
+
+	if len(errs.list()) != 0 {
+		t.Errorf("unexpected errors: %v", errs.list())
+	}
+	if len(received) != 2 {
+		t.Fatalf("received %d events, want 2 (ticks are not posted)", len(received))
+	}
+	if received[0].Type != EventBeep || received[0].Label != "work" {
+		t.Errorf("first event = %+v", received[0])
+	}
+	if received[1].Type != EventSegmentStart || received[1].Label != "break" {
+		t.Errorf("second event = %+v", received[1])
+	}
+}
+
+// TestWebhookRetries tests retry behaviour for failing endpoints
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int32
+		status       int
+		wantRequests int32
+		wantErrors   int
+	}{
+		{name: "recovers after server errors", failures: 2, status: http.StatusInternalServerError, wantRequests: 3},
+		{name: "gives up after max attempts", failures: 100, status: http.StatusBadGateway, wantRequests: webhookAttempts, wantErrors: 1},
+		{name: "retries rate limiting", failures: 1, status: http.StatusTooManyRequests, wantRequests: 2},
+		{name: "does not retry client errors", failures: 100, status: http.StatusBadRequest, wantRequests: 1, wantErrors: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) <= tt.failures {
+					w.WriteHeader(tt.status)
+				}
+			}))
+			defer server.Close()
+
+			webhook, errs := newTestWebhook(server.URL)
+			webhook.Send(Event{Type: EventBeep})
+			webhook.Close(5 * time.Second)
+
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			if got := len(errs.list()); got != tt.wantErrors {
+				t.Errorf("errors = %d, want %d", got, tt.wantErrors)
+			}
+		})
+	}
+}
+
+// TestWebhookTemplate tests custom request bodies
+func TestWebhookTemplate(t *testing.T) {
+	tmpl, err := parseWebhookTemplate(`{"text": {{printf "%s done after %s" .Label (duration (secs .Remaining)) | json}}}`)
+	if err != nil {
+		t.Fatalf("parseWebhookTemplate error: %v", err)
+	}
+
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies <- string(b)
+	}))
+	defer server.Close()
+
+	webhook, _ := newTestWebhook(server.URL)
+	webhook.Template = tmpl
+	webhook.Send(Event{Type: EventBeep, Label: `"work"`, Remaining: 90})
+	webhook.Close(5 * time.Second)
+
+	expected := `{"text": "\"work\" done after 1m 30s"}`
+	if body := <-bodies; body != expected {
+		t.Errorf("body = %s, want %s", body, expected)
+	}
+
+	if _, err := parseWebhookTemplate("{{.Nope}}"); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+// TestWebhookQueueFull tests that events are dropped instead of blocking
+func TestWebhookQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	webhook, errs := newTestWebhook(server.URL)
+
+	start := time.Now()
+	for i := 0; i < webhookQueueSize+5; i++ {
+		webhook.Send(Event{Type: EventBeep, BeepCount: i})
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Send blocked on a full queue")
+	}
+	close(release)
+	webhook.Close(5 * time.Second)
+
+	dropped := 0
+	for _, err := range errs.list() {
+		if strings.Contains(err.Error(), "queue full") {
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		t.Error("expected dropped events to be reported")
+	}
+}
+
+// TestWebhookCloseTimeout tests that Close abandons pending retries
+func TestWebhookCloseTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	webhook, _ := newTestWebhook(server.URL)
+	webhook.Backoff = time.Hour
+	webhook.Send(Event{Type: EventBeep})
+
+	start := time.Now()
+	webhook.Close(50 * time.Millisecond)
+	if time.Since(start) > 2*time.Second {
+		t.Error("Close did not abandon the pending retry")
+	}
+}
+
+// TestWebhookCloseCancels tests that Close cancels a hanging request and
+// drops the queued events instead of delivering them after the timeout
+func TestWebhookCloseCancels(t *testing.T) {
+	release := make(chan struct{})
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	webhook, errs := newTestWebhook(server.URL)
+	for i := 0; i < 5; i++ {
+		webhook.Send(Event{Type: EventBeep, BeepCount: i})
+	}
+
+	start := time.Now()
+	webhook.Close(50 * time.Millisecond)
+	if time.Since(start) > 2*time.Second {
+		t.Error("Close waited for the hanging request")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d requests sent, want 1", n)
+	}
+	var dropped bool
+	for _, err := range errs.list() {
+		dropped = dropped || strings.Contains(err.Error(), "dropping 4 queued events")
+	}
+	if !dropped {
+		t.Errorf("errors = %v, want the dropped events reported", errs.list())
+	}
+}
+
+// TestStringList tests the repeatable flag value
+func TestStringList(t *testing.T) {
+	var list stringList
+	list.Set("http://a")
+	list.Set("http://b")
+	if list.String() != "http://a,http://b" {
+		t.Errorf("String() = %q", list.String())
+	}
+}